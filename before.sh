@@ -22,5 +22,6 @@ sudo systemctl restart --now risucontest.service
 sudo systemctl restart --now mysql.service
 sudo systemctl restart --now nginx.service
 echo ":: START PPROF ====>"
+# risucontest.service に RISUCON_PROFILE_ADDR=:6060 を設定しておくこと
 go tool pprof -http=:1080 http://localhost:6060/debug/fgprof/profile?seconds=80
 # wait ってなったらsshを切る
\ No newline at end of file
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo-contrib/session"
+	"github.com/labstack/echo/v4"
+)
+
+// 削除したユーザーの名前。登録できる名前と被らないように ~ で始める
+const deletedUserNamePrefix = "~deleted-"
+
+// 削除したユーザーや、管理者がパスワードを再設定させているユーザーの passhash
+// calcsha256 はこの値を返さないので、どのパスワードでもログインできない
+const unusablePasshash = "!"
+
+type UpdateProfileRequest struct {
+	DisplayName string `json:"display_name"`
+	Description string `json:"description"`
+}
+
+// POST /api/account/profile
+func (s *Server) updateProfileHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+	defer c.Request().Body.Close()
+
+	username, err := loginUserName(c)
+	if err != nil {
+		return err
+	}
+	req := UpdateProfileRequest{}
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return newAPIError(http.StatusBadRequest, ErrCodeInvalidRequest, "failed to decode the request body as json")
+	}
+	req.DisplayName = normalizeText(req.DisplayName)
+	req.Description = normalizeText(req.Description)
+	errs := ValidationErrors{}
+	validateDisplayName(&errs, "display_name", req.DisplayName)
+	validateDescription(&errs, "description", req.Description)
+	if len(errs) > 0 {
+		return newAPIError(http.StatusBadRequest, ErrCodeValidationFailed, "invalid profile").withDetails(errs)
+	}
+
+	usr, err := s.store.Users().GetByName(ctx, username)
+	if err != nil {
+		return internalError("failed to get user", err)
+	}
+	usr.DisplayName = req.DisplayName
+	usr.Description = req.Description
+	if err := s.store.Users().Update(ctx, usr); err != nil {
+		return internalError("failed to update user", err)
+	}
+	s.cache.Publish(cacheEvent{Kind: cacheEventUserChanged, UserID: usr.ID})
+
+	return c.NoContent(http.StatusOK)
+}
+
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
+
+// POST /api/account/password
+// 他の端末のセッションはログアウトさせ、API トークンもすべて消す (漏れたときに変更する想定)
+// このリクエストのセッションはそのまま使える
+func (s *Server) changePasswordHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+	defer c.Request().Body.Close()
+
+	username, err := loginUserName(c)
+	if err != nil {
+		return err
+	}
+	req := ChangePasswordRequest{}
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return newAPIError(http.StatusBadRequest, ErrCodeInvalidRequest, "failed to decode the request body as json")
+	}
+	if req.NewPassword == "" {
+		return newAPIError(http.StatusBadRequest, ErrCodeValidationFailed, "invalid password").withDetails(ValidationErrors{
+			{Path: "new_password", Message: "must not be empty"},
+		})
+	}
+
+	usr, err := s.store.Users().GetByName(ctx, username)
+	if err != nil {
+		return internalError("failed to get user", err)
+	}
+	if usr.Passhash != calcsha256(req.OldPassword) {
+		return newAPIError(http.StatusBadRequest, ErrCodeWrongPassword, "old password is wrong")
+	}
+	usr.Passhash = calcsha256(req.NewPassword)
+	if err := s.store.Users().Update(ctx, usr); err != nil {
+		return internalError("failed to update user", err)
+	}
+	s.cache.Publish(cacheEvent{Kind: cacheEventUserChanged, UserID: usr.ID})
+
+	if err := s.store.APITokens().DeleteByUser(ctx, username); err != nil {
+		return internalError("failed to revoke api tokens", err)
+	}
+	if err := s.sessions.backend.DeleteByUser(ctx, username); err != nil {
+		return internalError("failed to revoke sessions", err)
+	}
+	// 消したうちの自分のセッションだけ保存し直す
+	sess, err := session.Get(defaultSessionIDKey, c)
+	if err != nil {
+		return internalError("failed to get session", err)
+	}
+	if err := sess.Save(c.Request(), c.Response()); err != nil {
+		return internalError("failed to save session", err)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
+// POST /api/account/delete
+// ユーザーは消さずに名前やプロフィールを消して匿名にする。提出はチームの得点として残す
+func (s *Server) deleteAccountHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+	defer c.Request().Body.Close()
+
+	username, err := loginUserName(c)
+	if err != nil {
+		return err
+	}
+	// admin を消すと管理者がいなくなり、DB を直接触らないと戻せない
+	if username == "admin" {
+		return newAPIError(http.StatusBadRequest, ErrCodeInvalidRequest, "admin account cannot be deleted")
+	}
+	req := DeleteAccountRequest{}
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return newAPIError(http.StatusBadRequest, ErrCodeInvalidRequest, "failed to decode the request body as json")
+	}
+
+	userID := 0
+	err = s.store.WithTx(ctx, func(st Store) error {
+		usr, err := st.Users().GetByName(ctx, username)
+		if err != nil {
+			return internalError("failed to get user", err)
+		}
+		if usr.Passhash != calcsha256(req.Password) {
+			return newAPIError(http.StatusBadRequest, ErrCodeWrongPassword, "password is wrong")
+		}
+		userID = usr.ID
+		err = st.Users().Update(ctx, User{
+			ID:          usr.ID,
+			Name:        deletedUserNamePrefix + strconv.Itoa(usr.ID),
+			DisplayName: "(deleted user)",
+			Description: "",
+			Passhash:    unusablePasshash,
+			Disabled:    true,
+		})
+		if err != nil {
+			return internalError("failed to anonymize user", err)
+		}
+		if err := st.APITokens().DeleteByUser(ctx, username); err != nil {
+			return internalError("failed to delete api tokens", err)
+		}
+		if err := st.Logins().DeleteByUser(ctx, username); err != nil {
+			return internalError("failed to delete login attempts", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return asAPIError("failed to commit transaction", err)
+	}
+	s.cache.Publish(cacheEvent{Kind: cacheEventUserChanged, UserID: userID})
+
+	// この名前で新しく登録した人にセッションが引き継がれないように、すべてログアウトさせる
+	if err := s.sessions.backend.DeleteByUser(ctx, username); err != nil {
+		return internalError("failed to revoke sessions", err)
+	}
+	sess, err := session.Get(defaultSessionIDKey, c)
+	if err != nil {
+		return internalError("failed to get session", err)
+	}
+	opts := sessionOptions(s.cfg.Session, -1)
+	sess.Options = &opts
+	if err := sess.Save(c.Request(), c.Response()); err != nil {
+		return internalError("failed to save session", err)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestDeleteAccountRejectsAdmin(t *testing.T) {
+	f := newFakeStore()
+	f.users = []User{{ID: 1, Name: "admin", DisplayName: "admin", Passhash: "x"}}
+	s := newTestServer(f)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/account/delete", strings.NewReader(`{"password":"admin"}`))
+	c := e.NewContext(req, httptest.NewRecorder())
+	c.Set(apiTokenUserKey, "admin")
+
+	err := s.deleteAccountHandler(c)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusBadRequest {
+		t.Fatalf("got %v, want 400", err)
+	}
+	if f.users[0].Name != "admin" {
+		t.Errorf("admin was renamed to %s", f.users[0].Name)
+	}
+}
@@ -1,11 +1,10 @@
 package main
 
 import (
-	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 
-	"github.com/labstack/echo-contrib/session"
 	"github.com/labstack/echo/v4"
 )
 
@@ -28,75 +27,110 @@ type CreateTaskRequest struct {
 }
 
 // POST /api/admin/createtask
-func createTaskHandler(c echo.Context) error {
+func (s *Server) createTaskHandler(c echo.Context) error {
 	ctx := c.Request().Context()
 	defer c.Request().Body.Close()
 
-	if err := verifyUserSession(c); err != nil {
+	if err := verifyAdminSession(c); err != nil {
 		return err
 	}
 
-	sess, _ := session.Get(defaultSessionIDKey, c)
-	username, _ := sess.Values[defaultSessionUserNameKey].(string)
-
-	if username != "admin" {
-		return echo.NewHTTPError(http.StatusUnauthorized, "not admin")
-	}
-
 	req := CreateTaskRequest{}
 	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "failed to decode the request body as json")
+		return newAPIError(http.StatusBadRequest, ErrCodeInvalidRequest, "failed to decode the request body as json")
 	}
 
-	tx, err := dbConn.BeginTxx(ctx, nil)
-	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to begin transaction: "+err.Error())
-	}
-	defer tx.Rollback()
-
-	task := Task{}
-	err = tx.GetContext(ctx, &task, "SELECT * FROM tasks WHERE name = ?", req.Name)
-	if err == nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "task already exists")
-	} else if err != sql.ErrNoRows {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get task: "+err.Error())
-	}
-	
-	if _, err := tx.ExecContext(ctx, "INSERT INTO tasks (name, display_name, statement, submission_limit) VALUES (?, ?, ?, ?)", req.Name, req.DisplayName, req.Statement, req.SubmissionLimit); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to insert task: "+err.Error())
-	}
-	var taskID int
-	err = tx.GetContext(ctx, &taskID, "SELECT id FROM tasks WHERE name = ?", req.Name)
-	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get taskID: "+err.Error())
+	if errs := validateCreateTaskRequest(req); len(errs) > 0 {
+		return newAPIError(http.StatusBadRequest, ErrCodeValidationFailed, "invalid task").withDetails(errs)
 	}
 
-	for _, subtask := range req.Subtasks {
-		subtasktmp := Subtask{}
-		err = tx.GetContext(ctx, &subtasktmp, "SELECT * FROM subtasks WHERE task_id = ? AND name = ?", taskID, req.Name)
+	taskID := 0
+	err := s.store.WithTx(ctx, func(st Store) error {
+		_, err := st.Tasks().GetByName(ctx, req.Name)
 		if err == nil {
-			return echo.NewHTTPError(http.StatusBadRequest, "subtask already exists")
-		} else if err != sql.ErrNoRows {
-			return echo.NewHTTPError(http.StatusInternalServerError, "failed to get subtask: "+err.Error())
+			return newAPIError(http.StatusBadRequest, ErrCodeTaskAlreadyExists, "task already exists")
+		} else if !errors.Is(err, ErrNotFound) {
+			return internalError("failed to get task", err)
 		}
-		if _, err := tx.ExecContext(ctx, "INSERT INTO subtasks (name, display_name, task_id, statement) VALUES (?, ?, ?, ?)", subtask.Name, subtask.DisplayName, taskID, subtask.Statement); err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "failed to insert subtask: "+err.Error())
-		}
-		var subtaskID int
-		err = tx.GetContext(ctx, &subtaskID, "SELECT id FROM subtasks WHERE task_id = ? AND name = ?", taskID, subtask.Name)
+
+		taskID, err = st.Tasks().Create(ctx, Task{Name: req.Name, DisplayName: req.DisplayName, Statement: req.Statement, SubmissionLimit: req.SubmissionLimit})
 		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "failed to get subtaskID: "+err.Error())
+			return internalError("failed to insert task", err)
 		}
-		for _, answer := range subtask.Answers {
-			if _, err := tx.ExecContext(ctx, "INSERT INTO answers (task_id, subtask_id, answer, score) VALUES (?, ?, ?, ?)", taskID, subtaskID, answer.Answer, answer.Score); err != nil {
-				return echo.NewHTTPError(http.StatusInternalServerError, "failed to insert answer: "+err.Error())
+
+		for _, subtask := range req.Subtasks {
+			_, err = st.Tasks().GetSubtaskByName(ctx, taskID, subtask.Name)
+			if err == nil {
+				return newAPIError(http.StatusBadRequest, ErrCodeSubtaskAlreadyExists, "subtask already exists")
+			} else if !errors.Is(err, ErrNotFound) {
+				return internalError("failed to get subtask", err)
+			}
+			subtaskID, err := st.Tasks().CreateSubtask(ctx, Subtask{Name: subtask.Name, DisplayName: subtask.DisplayName, TaskID: taskID, Statement: subtask.Statement})
+			if err != nil {
+				return internalError("failed to insert subtask", err)
+			}
+			for _, answer := range subtask.Answers {
+				if err := st.Tasks().CreateAnswer(ctx, Answer{TaskID: taskID, SubtaskID: subtaskID, Answer: answer.Answer, Score: answer.Score}); err != nil {
+					return internalError("failed to insert answer", err)
+				}
 			}
 		}
+		return nil
+	})
+	if err != nil {
+		return asAPIError("failed to commit transaction", err)
 	}
+	s.cache.Publish(cacheEvent{Kind: cacheEventTaskCreated, TaskID: taskID})
 
-	if err := tx.Commit(); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to commit transaction: "+err.Error())
+	return c.NoContent(http.StatusCreated)
+}
+
+// 管理者であることに加えて、サーバー側で RISUCON_ALLOW_RESET が有効になっている必要がある
+func (s *Server) verifyResetAllowed(c echo.Context) error {
+	if err := verifyAdminSession(c); err != nil {
+		return err
+	}
+	if !s.cfg.Server.AllowReset {
+		return newAPIError(http.StatusForbidden, ErrCodeResetDisabled, "reset is disabled on this server")
 	}
+	return nil
+}
 
-	return c.NoContent(http.StatusCreated)
+// POST /api/admin/reset/seed
+func (s *Server) resetSeedHandler(c echo.Context) error {
+	if err := s.verifyResetAllowed(c); err != nil {
+		return err
+	}
+	if err := s.resetToSeed(c.Request().Context()); err != nil {
+		return internalError("failed to reset to seed", err)
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// POST /api/admin/reset/submissions
+func (s *Server) resetSubmissionsHandler(c echo.Context) error {
+	if err := s.verifyResetAllowed(c); err != nil {
+		return err
+	}
+	if err := s.resetSubmissions(c.Request().Context()); err != nil {
+		return internalError("failed to reset submissions", err)
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// POST /api/admin/reload-caches
+func (s *Server) reloadCachesHandler(c echo.Context) error {
+	if err := verifyAdminSession(c); err != nil {
+		return err
+	}
+	s.cache.Clear()
+	return c.NoContent(http.StatusOK)
+}
+
+// GET /api/admin/cache-stats
+func (s *Server) getCacheStatsHandler(c echo.Context) error {
+	if err := verifyAdminSession(c); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, s.cache.Stats())
 }
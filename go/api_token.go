@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// API トークンで認証したリクエストでは、トークンの持ち主の名前を c.Set しておく
+const apiTokenUserKey = "api_token_user"
+
+// トークンの先頭に付ける。ログや設定ファイルに紛れ込んだときに見つけやすくする
+const apiTokenPrefix = "risu_"
+
+// 1 人が持てるトークンの数
+const maxAPITokensPerUser = 20
+
+const (
+	scopeReadStandings   = "read-standings"
+	scopeReadTasks       = "read-tasks"
+	scopeReadSubmissions = "read-submissions"
+	scopeSubmit          = "submit"
+)
+
+var apiTokenScopes = []string{scopeReadStandings, scopeReadTasks, scopeReadSubmissions, scopeSubmit}
+
+// トークンで呼べるエンドポイントと、それに必要なスコープ。ここにないものはブラウザでログインしないと使えない
+var apiTokenRouteScopes = map[string]string{
+	"GET /api/standings":               scopeReadStandings,
+	"GET /api/standings/progress":      scopeReadStandings,
+	"GET /api/tasks":                   scopeReadTasks,
+	"GET /api/tasks/:taskname":         scopeReadTasks,
+	"GET /api/tasks/:taskname/history": scopeReadSubmissions,
+	"GET /api/submissions":             scopeReadSubmissions,
+	"POST /api/submit":                 scopeSubmit,
+}
+
+type APIToken struct {
+	ID        int       `db:"id"`
+	UserName  string    `db:"user_name"`
+	Name      string    `db:"name"`
+	TokenHash string    `db:"token_hash"`
+	Scopes    string    `db:"scopes"` // カンマ区切り
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func (t APIToken) scopeList() []string {
+	return strings.Split(t.Scopes, ",")
+}
+
+// Authorization: Bearer <token> が付いていれば、セッションの代わりにトークンで認証する
+// 以降の verifyUserSession や loginUserName はトークンの持ち主としてログインしているものとして動く
+func (s *Server) apiTokenAuth() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			raw, ok := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+			if !ok {
+				return next(c)
+			}
+			token, err := s.store.APITokens().GetByHash(c.Request().Context(), hashSessionToken(strings.TrimSpace(raw)))
+			if errors.Is(err, ErrNotFound) {
+				return newAPIError(http.StatusUnauthorized, ErrCodeInvalidToken, "invalid api token")
+			} else if err != nil {
+				return internalError("failed to get api token", err)
+			}
+			// 無効にしたときにトークンは消しているが、念のため持ち主も確かめる
+			usr, err := s.store.Users().GetByName(c.Request().Context(), token.UserName)
+			if errors.Is(err, ErrNotFound) {
+				return newAPIError(http.StatusUnauthorized, ErrCodeInvalidToken, "invalid api token")
+			} else if err != nil {
+				return internalError("failed to get user", err)
+			}
+			if usr.Disabled {
+				return newAPIError(http.StatusForbidden, ErrCodeAccountDisabled, "account is disabled")
+			}
+			scope, ok := apiTokenRouteScopes[c.Request().Method+" "+c.Path()]
+			if !ok {
+				return newAPIError(http.StatusForbidden, ErrCodeInsufficientScope, "this endpoint cannot be used with an api token")
+			}
+			if !slices.Contains(token.scopeList(), scope) {
+				return newAPIError(http.StatusForbidden, ErrCodeInsufficientScope, "api token does not have the "+scope+" scope")
+			}
+			c.Set(apiTokenUserKey, token.UserName)
+			return next(c)
+		}
+	}
+}
+
+type CreateAPITokenRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+type APITokenResponse struct {
+	ID        int      `json:"id"`
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	CreatedAt int64    `json:"created_at"`
+	// 作成したときにだけ返す。サーバーにはハッシュしか残らない
+	Token string `json:"token,omitempty"`
+}
+
+func validateCreateAPITokenRequest(req CreateAPITokenRequest) ValidationErrors {
+	errs := ValidationErrors{}
+	validateName(&errs, "name", req.Name)
+	if len(req.Scopes) == 0 {
+		errs.add("scopes", "must not be empty")
+	}
+	for _, scope := range req.Scopes {
+		if !slices.Contains(apiTokenScopes, scope) {
+			errs.add("scopes", "unknown scope "+scope+" (available: "+strings.Join(apiTokenScopes, ", ")+")")
+		}
+	}
+	return errs
+}
+
+// POST /api/account/tokens
+func (s *Server) createAPITokenHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+	defer c.Request().Body.Close()
+
+	username, err := loginUserName(c)
+	if err != nil {
+		return err
+	}
+	req := CreateAPITokenRequest{}
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return newAPIError(http.StatusBadRequest, ErrCodeInvalidRequest, "failed to decode the request body as json")
+	}
+	if errs := validateCreateAPITokenRequest(req); len(errs) > 0 {
+		return newAPIError(http.StatusBadRequest, ErrCodeValidationFailed, "invalid token").withDetails(errs)
+	}
+	slices.Sort(req.Scopes)
+	req.Scopes = slices.Compact(req.Scopes)
+
+	tokens, err := s.store.APITokens().ListByUser(ctx, username)
+	if err != nil {
+		return internalError("failed to get api tokens", err)
+	}
+	if len(tokens) >= maxAPITokensPerUser {
+		return newAPIError(http.StatusBadRequest, ErrCodeTooManyTokens, "too many api tokens")
+	}
+
+	raw, err := randomToken()
+	if err != nil {
+		return internalError("failed to generate api token", err)
+	}
+	raw = apiTokenPrefix + raw
+	token := APIToken{
+		UserName:  username,
+		Name:      req.Name,
+		TokenHash: hashSessionToken(raw),
+		Scopes:    strings.Join(req.Scopes, ","),
+		CreatedAt: dbNow(),
+	}
+	id, err := s.store.APITokens().Create(ctx, token)
+	if err != nil {
+		return internalError("failed to create api token", err)
+	}
+	return c.JSON(http.StatusCreated, APITokenResponse{
+		ID:        id,
+		Name:      token.Name,
+		Scopes:    req.Scopes,
+		CreatedAt: token.CreatedAt.Unix(),
+		Token:     raw,
+	})
+}
+
+// GET /api/account/tokens
+func (s *Server) getAPITokensHandler(c echo.Context) error {
+	username, err := loginUserName(c)
+	if err != nil {
+		return err
+	}
+	tokens, err := s.store.APITokens().ListByUser(c.Request().Context(), username)
+	if err != nil {
+		return internalError("failed to get api tokens", err)
+	}
+	res := make([]APITokenResponse, 0, len(tokens))
+	for _, t := range tokens {
+		res = append(res, APITokenResponse{
+			ID:        t.ID,
+			Name:      t.Name,
+			Scopes:    t.scopeList(),
+			CreatedAt: t.CreatedAt.Unix(),
+		})
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+type RevokeAPITokenRequest struct {
+	ID int `json:"id"`
+}
+
+// POST /api/account/tokens/revoke
+func (s *Server) revokeAPITokenHandler(c echo.Context) error {
+	defer c.Request().Body.Close()
+
+	username, err := loginUserName(c)
+	if err != nil {
+		return err
+	}
+	req := RevokeAPITokenRequest{}
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return newAPIError(http.StatusBadRequest, ErrCodeInvalidRequest, "failed to decode the request body as json")
+	}
+	deleted, err := s.store.APITokens().Delete(c.Request().Context(), req.ID, username)
+	if err != nil {
+		return internalError("failed to revoke api token", err)
+	}
+	if !deleted {
+		return newAPIError(http.StatusNotFound, ErrCodeTokenNotFound, "api token not found")
+	}
+	return c.NoContent(http.StatusOK)
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultCacheTTL        = 5 * time.Minute
+	defaultCacheMaxEntries = 10000
+)
+
+type CacheStats struct {
+	Name    string `json:"name"`
+	Hits    uint64 `json:"hits"`
+	Misses  uint64 `json:"misses"`
+	Entries int    `json:"entries"`
+}
+
+type cacheEntry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// 名前付きのキャッシュ領域。ttl が 0 なら期限切れにならない
+type cacheRegion[K comparable, V any] struct {
+	name       string
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.RWMutex
+	entries map[K]cacheEntry[V]
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+func newCacheRegion[K comparable, V any](name string, ttl time.Duration, maxEntries int) *cacheRegion[K, V] {
+	return &cacheRegion[K, V]{
+		name:       name,
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    map[K]cacheEntry[V]{},
+	}
+}
+
+func (r *cacheRegion[K, V]) Get(key K) (V, bool) {
+	r.mu.RLock()
+	entry, ok := r.entries[key]
+	r.mu.RUnlock()
+	if !ok || (!entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt)) {
+		r.misses.Add(1)
+		var zero V
+		return zero, false
+	}
+	r.hits.Add(1)
+	return entry.value, true
+}
+
+func (r *cacheRegion[K, V]) Set(key K, value V) {
+	entry := cacheEntry[V]{value: value}
+	if r.ttl > 0 {
+		entry.expiresAt = time.Now().Add(r.ttl)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.entries[key]; !ok && r.maxEntries > 0 && len(r.entries) >= r.maxEntries {
+		r.evictLocked()
+	}
+	r.entries[key] = entry
+}
+
+// 期限切れのものを消し、それでも空かなければ適当に 1 つ消す
+func (r *cacheRegion[K, V]) evictLocked() {
+	now := time.Now()
+	for k, e := range r.entries {
+		if !e.expiresAt.IsZero() && now.After(e.expiresAt) {
+			delete(r.entries, k)
+		}
+	}
+	if len(r.entries) < r.maxEntries {
+		return
+	}
+	for k := range r.entries {
+		delete(r.entries, k)
+		return
+	}
+}
+
+func (r *cacheRegion[K, V]) Delete(key K) {
+	r.mu.Lock()
+	delete(r.entries, key)
+	r.mu.Unlock()
+}
+
+func (r *cacheRegion[K, V]) DeleteFunc(match func(K) bool) {
+	r.mu.Lock()
+	for k := range r.entries {
+		if match(k) {
+			delete(r.entries, k)
+		}
+	}
+	r.mu.Unlock()
+}
+
+func (r *cacheRegion[K, V]) Clear() {
+	r.mu.Lock()
+	r.entries = map[K]cacheEntry[V]{}
+	r.mu.Unlock()
+}
+
+func (r *cacheRegion[K, V]) Stats() CacheStats {
+	r.mu.RLock()
+	entries := len(r.entries)
+	r.mu.RUnlock()
+	return CacheStats{
+		Name:    r.name,
+		Hits:    r.hits.Load(),
+		Misses:  r.misses.Load(),
+		Entries: entries,
+	}
+}
+
+type teamTaskKey struct {
+	TeamID int
+	TaskID int
+}
+
+type cacheEventKind int
+
+const (
+	cacheEventSubmissionCreated cacheEventKind = iota
+	cacheEventTaskCreated
+	cacheEventTeamChanged
+	cacheEventUserChanged
+	cacheEventReset
+)
+
+// 書き込みのあとに Caches.Publish に渡す。使わないフィールドは 0 のまま
+type cacheEvent struct {
+	Kind   cacheEventKind
+	TeamID int
+	TaskID int
+	UserID int
+	Scored bool // cacheEventSubmissionCreated のとき、点数が付いたかどうか
+}
+
+// サーバー全体のキャッシュ。各領域は Publish されたイベントを見て自分で無効化する
+type Caches struct {
+	Subtasks          *cacheRegion[int, []Subtask] // task_id -> subtasks
+	SubtaskMaxScore   *cacheRegion[int, int]       // subtask_id -> 満点
+	Users             *cacheRegion[int, User]      // user_id -> user
+	UsersByName       *cacheRegion[string, User]   // name -> user (セッションのユーザーを確かめる用)
+	TeamTaskScore     *cacheRegion[teamTaskKey, int]
+	TeamTaskSubmitted *cacheRegion[teamTaskKey, bool]
+
+	hooks []func(cacheEvent)
+	stats []func() CacheStats
+}
+
+func newCaches(ttl time.Duration, maxEntries int) *Caches {
+	c := &Caches{
+		Subtasks:          newCacheRegion[int, []Subtask]("subtasks", ttl, maxEntries),
+		SubtaskMaxScore:   newCacheRegion[int, int]("subtask_max_score", ttl, maxEntries),
+		Users:             newCacheRegion[int, User]("users", ttl, maxEntries),
+		UsersByName:       newCacheRegion[string, User]("users_by_name", ttl, maxEntries),
+		TeamTaskScore:     newCacheRegion[teamTaskKey, int]("team_task_score", ttl, maxEntries),
+		TeamTaskSubmitted: newCacheRegion[teamTaskKey, bool]("team_task_submitted", ttl, maxEntries),
+	}
+	c.stats = []func() CacheStats{
+		c.Subtasks.Stats,
+		c.SubtaskMaxScore.Stats,
+		c.Users.Stats,
+		c.UsersByName.Stats,
+		c.TeamTaskScore.Stats,
+		c.TeamTaskSubmitted.Stats,
+	}
+
+	c.OnEvent(func(ev cacheEvent) {
+		switch ev.Kind {
+		case cacheEventSubmissionCreated:
+			c.TeamTaskSubmitted.Set(teamTaskKey{ev.TeamID, ev.TaskID}, true)
+			if ev.Scored {
+				c.TeamTaskScore.Delete(teamTaskKey{ev.TeamID, ev.TaskID})
+			}
+		case cacheEventTaskCreated:
+			// サブタスクの ID はまだどこにもキャッシュされていないので、タスク単位のものだけ消す
+			c.Subtasks.Delete(ev.TaskID)
+		case cacheEventTeamChanged:
+			// メンバーが変わるとチームの提出の集計対象も変わる
+			sameTeam := func(k teamTaskKey) bool { return k.TeamID == ev.TeamID }
+			c.TeamTaskScore.DeleteFunc(sameTeam)
+			c.TeamTaskSubmitted.DeleteFunc(sameTeam)
+		case cacheEventUserChanged:
+			c.Users.Delete(ev.UserID)
+			// 名前が変わる (アカウント削除) こともあり、前の名前はわからないので全部消す。ユーザーの変更は少ない
+			c.UsersByName.Clear()
+		case cacheEventReset:
+			c.Subtasks.Clear()
+			c.SubtaskMaxScore.Clear()
+			c.Users.Clear()
+			c.UsersByName.Clear()
+			c.TeamTaskScore.Clear()
+			c.TeamTaskSubmitted.Clear()
+		}
+	})
+	return c
+}
+
+// 書き込み系の処理から呼ばれるフックを追加する
+func (c *Caches) OnEvent(hook func(cacheEvent)) {
+	c.hooks = append(c.hooks, hook)
+}
+
+func (c *Caches) Publish(ev cacheEvent) {
+	for _, hook := range c.hooks {
+		hook(ev)
+	}
+}
+
+func (c *Caches) Clear() {
+	c.Publish(cacheEvent{Kind: cacheEventReset})
+}
+
+func (c *Caches) Stats() []CacheStats {
+	res := make([]CacheStats, 0, len(c.stats))
+	for _, f := range c.stats {
+		res = append(res, f())
+	}
+	return res
+}
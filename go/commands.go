@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// DB を初期データの状態に戻し、提出を採点し直す (ベンチマーク用)
+func (s *Server) resetToSeed(ctx context.Context) error {
+	if err := s.store.Reset(ctx); err != nil {
+		return err
+	}
+	// session.store が memory のときは DB を消してもセッションが残る
+	if err := s.sessions.backend.DeleteAll(ctx); err != nil {
+		return fmt.Errorf("failed to delete sessions: %w", err)
+	}
+	// score
+	subs, err := s.store.Submissions().List(ctx, SubmissionFilter{})
+	if err != nil {
+		return fmt.Errorf("failed to select submissions: %w", err)
+	}
+	for _, sub := range subs {
+		ans, err := s.store.Tasks().FindAnswer(ctx, sub.TaskID, sub.Answer)
+		if errors.Is(err, ErrNotFound) {
+			ans.Score = 0
+			ans.SubtaskID = -1
+		} else if err != nil {
+			return fmt.Errorf("failed to select answers: %w", err)
+		}
+		if err := s.store.Submissions().UpdateScore(ctx, sub.ID, ans.SubtaskID, ans.Score); err != nil {
+			return fmt.Errorf("failed to update submissions: %w", err)
+		}
+	}
+	if err := recomputeFirstSolves(ctx, s.store); err != nil {
+		return err
+	}
+	// キャッシュを消す
+	s.cache.Clear()
+	return nil
+}
+
+// ユーザー・チーム・問題は残して提出だけを消す
+// 途中で失敗したときに first blood だけが残らないよう、まとめて消す
+func (s *Server) resetSubmissions(ctx context.Context) error {
+	err := s.store.WithTx(ctx, func(st Store) error {
+		if err := st.Submissions().DeleteAll(ctx); err != nil {
+			return fmt.Errorf("failed to delete submissions: %w", err)
+		}
+		if err := st.FirstSolves().DeleteAll(ctx); err != nil {
+			return fmt.Errorf("failed to delete first solves: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// コミットしてから消す。先に消すと、コミット前の古い提出が読まれてキャッシュに入り直す
+	s.cache.Clear()
+	return nil
+}
+
+// ./risucontest <command>
+// CLI からの操作は別プロセスなので、最後に起動中のサーバーへ SIGHUP を送ってキャッシュを消させる
+func (s *Server) runCommand(ctx context.Context, args []string) error {
+	switch args[0] {
+	case "reset-seed":
+		if err := s.resetToSeed(ctx); err != nil {
+			return err
+		}
+	case "reset-submissions":
+		if err := s.resetSubmissions(ctx); err != nil {
+			return err
+		}
+	case "reload-caches":
+	default:
+		return fmt.Errorf("unknown command: %s (available: migrate, reset-seed, reset-submissions, reload-caches)", args[0])
+	}
+	return s.signalRunningServer()
+}
+
+// 起動中のサーバーの PID。CLI からキャッシュを消すときに SIGHUP を送る先
+func (s *Server) writePIDFile() error {
+	return os.WriteFile(s.cfg.Server.PIDFile, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644)
+}
+
+func (s *Server) signalRunningServer() error {
+	pidFile := s.cfg.Server.PIDFile
+	b, err := os.ReadFile(pidFile)
+	if errors.Is(err, os.ErrNotExist) {
+		// サーバーが起動していなければキャッシュもないので何もしなくてよい
+		return nil
+	} else if err != nil {
+		return err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		return fmt.Errorf("invalid pid file %s: %w", pidFile, err)
+	}
+	if err := syscall.Kill(pid, syscall.SIGHUP); err != nil && !errors.Is(err, syscall.ESRCH) {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) reloadCachesOnSignal() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	for range ch {
+		s.cache.Clear()
+	}
+}
@@ -0,0 +1,420 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 設定は デフォルト値 -> 設定ファイル (YAML) -> 環境変数 -> コマンドライン引数 の順に上書きされる
+type Config struct {
+	// production では既定のセッションの秘密鍵のままでは起動しない
+	Mode       string           `yaml:"mode"`
+	Server     ServerConfig     `yaml:"server"`
+	DB         DBConfig         `yaml:"db"`
+	Session    SessionConfig    `yaml:"session"`
+	Cache      CacheConfig      `yaml:"cache"`
+	Pagination PaginationConfig `yaml:"pagination"`
+	Contest    ContestConfig    `yaml:"contest"`
+	Log        LogConfig        `yaml:"log"`
+	RateLimit  RateLimitConfig  `yaml:"rate_limit"`
+	Login      LoginConfig      `yaml:"login"`
+	// 管理者が /api/admin/registration で変更するまでの初期値
+	Registration RegistrationConfig `yaml:"registration"`
+}
+
+type ServerConfig struct {
+	ListenAddr  string `yaml:"listen_addr"`
+	StaticPath  string `yaml:"static_path"`
+	PIDFile     string `yaml:"pid_file"`
+	MetricsAddr string `yaml:"metrics_addr"`
+	// DB を消す操作を HTTP から受け付けるかどうか
+	AllowReset        bool   `yaml:"allow_reset"`
+	ProfileAddr       string `yaml:"profile_addr"`
+	ProfileOnMainPort bool   `yaml:"profile_on_main_port"`
+	// SIGTERM を受けてから処理中のリクエストを待つ時間
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+type DBConfig struct {
+	Driver          string        `yaml:"driver"`
+	Host            string        `yaml:"host"`
+	Port            string        `yaml:"port"`
+	User            string        `yaml:"user"`
+	Password        string        `yaml:"password"`
+	Name            string        `yaml:"name"`
+	SQLitePath      string        `yaml:"sqlite_path"`
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnectTimeout  time.Duration `yaml:"connect_timeout"`
+	ReadTimeout     time.Duration `yaml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout"`
+	AutoMigrate     bool          `yaml:"auto_migrate"`
+}
+
+// ソースに書いてあるので誰でも知っている。開発用
+const defaultSessionSecret = "risucon_session_cookiestore_defaultsecret"
+
+type SessionConfig struct {
+	// db か memory (再起動すると全員ログアウトされる)
+	Store    string `yaml:"store"`
+	Secret   string `yaml:"secret"`
+	MaxAge   int    `yaml:"max_age"` // 秒
+	Secure   bool   `yaml:"secure"`
+	SameSite string `yaml:"same_site"` // lax, strict, none
+	// クッキーでログインしているときの POST に X-CSRF-Token ヘッダーを要求する
+	// ヘッダーを付けないクライアント (古いベンチマーカーなど) を使うときは false にする
+	CSRF bool `yaml:"csrf"`
+}
+
+func (cfg SessionConfig) sameSite() http.SameSite {
+	switch cfg.SameSite {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
+
+type CacheConfig struct {
+	TTL        time.Duration `yaml:"ttl"`
+	MaxEntries int           `yaml:"max_entries"`
+}
+
+type PaginationConfig struct {
+	SubmissionsPerPage int `yaml:"submissions_per_page"`
+	// 管理者のユーザー一覧
+	UsersPerPage int `yaml:"users_per_page"`
+}
+
+type ContestConfig struct {
+	// 招待コードのバイト数 (16 進数なので文字数はこの 2 倍)
+	InvitationCodeBytes int `yaml:"invitation_code_bytes"`
+}
+
+type LogConfig struct {
+	// json か ltsv (alp で集計するとき)
+	Format string `yaml:"format"`
+	// 空なら標準出力
+	AccessLogPath string `yaml:"access_log_path"`
+}
+
+// interval を 0 にすると制限しない。ベンチマーカーは 1 つの IP から大量に送ってくるのでデフォルトでは無効
+type RateLimitConfig struct {
+	// ログイン・登録: IP ごとに interval に 1 回、最大 burst 回まで連続で
+	LoginInterval time.Duration `yaml:"login_interval"`
+	LoginBurst    int           `yaml:"login_burst"`
+	// 提出: チームごとに interval に 1 回、最大 burst 回まで連続で
+	SubmitInterval time.Duration `yaml:"submit_interval"`
+	SubmitBurst    int           `yaml:"submit_burst"`
+	// 同じチームが同じ問題に提出するときの最小間隔
+	SubmitTaskInterval time.Duration `yaml:"submit_task_interval"`
+}
+
+// failure_window の間に max_failures 回パスワードを間違えたら lockout_duration の間ログインできなくする
+// max_failures を 0 にするとロックしない (試行の記録は残る)
+type LoginConfig struct {
+	MaxFailures     int           `yaml:"max_failures"`
+	FailureWindow   time.Duration `yaml:"failure_window"`
+	LockoutDuration time.Duration `yaml:"lockout_duration"`
+	// 管理者が発行したパスワード再設定用トークンの有効期限
+	PasswordResetTTL time.Duration `yaml:"password_reset_ttl"`
+}
+
+type RegistrationConfig struct {
+	// open / closed / code (registration_code が必要) / allowlist (管理者が登録した名前だけ)
+	Mode string `yaml:"mode"`
+	Code string `yaml:"code"`
+}
+
+func defaultConfig() Config {
+	return Config{
+		Mode: "development",
+		Server: ServerConfig{
+			ListenAddr:      ":8080",
+			StaticPath:      "../public",
+			PIDFile:         "risucontest.pid",
+			ShutdownTimeout: 10 * time.Second,
+		},
+		DB: DBConfig{
+			Driver:         "mysql",
+			Host:           "127.0.0.1",
+			Port:           "3306",
+			User:           "risucon",
+			Password:       "risucon",
+			Name:           "risucontest",
+			SQLitePath:     "risucontest.db",
+			MaxOpenConns:   1000,
+			MaxIdleConns:   2,
+			ConnectTimeout: 10 * time.Second,
+			AutoMigrate:    true,
+		},
+		Session: SessionConfig{
+			Store:    "db",
+			Secret:   defaultSessionSecret,
+			MaxAge:   86400 * 7,
+			SameSite: "lax",
+			CSRF:     true,
+		},
+		Cache: CacheConfig{
+			TTL:        defaultCacheTTL,
+			MaxEntries: defaultCacheMaxEntries,
+		},
+		Pagination: PaginationConfig{
+			SubmissionsPerPage: 20,
+			UsersPerPage:       50,
+		},
+		Contest: ContestConfig{
+			InvitationCodeBytes: 8,
+		},
+		Log: LogConfig{
+			Format: "json",
+		},
+		RateLimit: RateLimitConfig{
+			LoginBurst:  10,
+			SubmitBurst: 10,
+		},
+		Login: LoginConfig{
+			MaxFailures:      5,
+			FailureWindow:    15 * time.Minute,
+			LockoutDuration:  15 * time.Minute,
+			PasswordResetTTL: 24 * time.Hour,
+		},
+		Registration: RegistrationConfig{
+			Mode: registrationModeOpen,
+		},
+	}
+}
+
+func (cfg *Config) loadFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	// 綴り間違いに気づけるように、知らないキーはエラーにする
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return nil
+}
+
+// 今までの RISUCON_* の環境変数はそのまま使える
+func (cfg *Config) loadEnv() error {
+	errs := []error{}
+	str := func(key string, dst *string) {
+		if v, ok := os.LookupEnv(key); ok {
+			*dst = v
+		}
+	}
+	integer := func(key string, dst *int) {
+		if v, ok := os.LookupEnv(key); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", key, err))
+				return
+			}
+			*dst = n
+		}
+	}
+	boolean := func(key string, dst *bool) {
+		if v, ok := os.LookupEnv(key); ok {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", key, err))
+				return
+			}
+			*dst = b
+		}
+	}
+	duration := func(key string, dst *time.Duration) {
+		if v, ok := os.LookupEnv(key); ok {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", key, err))
+				return
+			}
+			*dst = d
+		}
+	}
+
+	str("RISUCON_MODE", &cfg.Mode)
+
+	str("RISUCON_LISTEN_ADDR", &cfg.Server.ListenAddr)
+	str("RISUCON_STATIC_PATH", &cfg.Server.StaticPath)
+	str("RISUCON_PID_FILE", &cfg.Server.PIDFile)
+	str("RISUCON_METRICS_ADDR", &cfg.Server.MetricsAddr)
+	boolean("RISUCON_ALLOW_RESET", &cfg.Server.AllowReset)
+	str("RISUCON_PROFILE_ADDR", &cfg.Server.ProfileAddr)
+	boolean("RISUCON_PROFILE_ON_MAIN_PORT", &cfg.Server.ProfileOnMainPort)
+	duration("RISUCON_SHUTDOWN_TIMEOUT", &cfg.Server.ShutdownTimeout)
+
+	str("RISUCON_DB_DRIVER", &cfg.DB.Driver)
+	str("RISUCON_DB_HOST", &cfg.DB.Host)
+	str("RISUCON_DB_PORT", &cfg.DB.Port)
+	str("RISUCON_DB_USER", &cfg.DB.User)
+	str("RISUCON_DB_PASSWORD", &cfg.DB.Password)
+	str("RISUCON_DB_NAME", &cfg.DB.Name)
+	str("RISUCON_SQLITE_PATH", &cfg.DB.SQLitePath)
+	integer("RISUCON_DB_MAX_OPEN_CONNS", &cfg.DB.MaxOpenConns)
+	integer("RISUCON_DB_MAX_IDLE_CONNS", &cfg.DB.MaxIdleConns)
+	duration("RISUCON_DB_CONN_MAX_LIFETIME", &cfg.DB.ConnMaxLifetime)
+	duration("RISUCON_DB_CONNECT_TIMEOUT", &cfg.DB.ConnectTimeout)
+	duration("RISUCON_DB_READ_TIMEOUT", &cfg.DB.ReadTimeout)
+	duration("RISUCON_DB_WRITE_TIMEOUT", &cfg.DB.WriteTimeout)
+	boolean("RISUCON_AUTO_MIGRATE", &cfg.DB.AutoMigrate)
+
+	str("RISUCON_SESSION_STORE", &cfg.Session.Store)
+	str("RISUCON_SESSION_SECRETKEY", &cfg.Session.Secret)
+	integer("RISUCON_SESSION_MAX_AGE", &cfg.Session.MaxAge)
+	boolean("RISUCON_SESSION_SECURE", &cfg.Session.Secure)
+	str("RISUCON_SESSION_SAME_SITE", &cfg.Session.SameSite)
+	boolean("RISUCON_SESSION_CSRF", &cfg.Session.CSRF)
+
+	duration("RISUCON_CACHE_TTL", &cfg.Cache.TTL)
+	integer("RISUCON_CACHE_MAX_ENTRIES", &cfg.Cache.MaxEntries)
+
+	integer("RISUCON_SUBMISSIONS_PER_PAGE", &cfg.Pagination.SubmissionsPerPage)
+	integer("RISUCON_USERS_PER_PAGE", &cfg.Pagination.UsersPerPage)
+	integer("RISUCON_INVITATION_CODE_BYTES", &cfg.Contest.InvitationCodeBytes)
+
+	str("RISUCON_LOG_FORMAT", &cfg.Log.Format)
+	str("RISUCON_ACCESS_LOG_PATH", &cfg.Log.AccessLogPath)
+
+	duration("RISUCON_RATE_LIMIT_LOGIN_INTERVAL", &cfg.RateLimit.LoginInterval)
+	integer("RISUCON_RATE_LIMIT_LOGIN_BURST", &cfg.RateLimit.LoginBurst)
+	duration("RISUCON_RATE_LIMIT_SUBMIT_INTERVAL", &cfg.RateLimit.SubmitInterval)
+	integer("RISUCON_RATE_LIMIT_SUBMIT_BURST", &cfg.RateLimit.SubmitBurst)
+	duration("RISUCON_RATE_LIMIT_SUBMIT_TASK_INTERVAL", &cfg.RateLimit.SubmitTaskInterval)
+
+	integer("RISUCON_LOGIN_MAX_FAILURES", &cfg.Login.MaxFailures)
+	duration("RISUCON_LOGIN_FAILURE_WINDOW", &cfg.Login.FailureWindow)
+	duration("RISUCON_LOGIN_LOCKOUT_DURATION", &cfg.Login.LockoutDuration)
+	duration("RISUCON_PASSWORD_RESET_TTL", &cfg.Login.PasswordResetTTL)
+
+	str("RISUCON_REGISTRATION_MODE", &cfg.Registration.Mode)
+	str("RISUCON_REGISTRATION_CODE", &cfg.Registration.Code)
+
+	return errors.Join(errs...)
+}
+
+func (cfg *Config) validate() error {
+	errs := []error{}
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	check(cfg.Server.ListenAddr != "", "server.listen_addr must not be empty")
+	check(cfg.Server.StaticPath != "", "server.static_path must not be empty")
+	check(cfg.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+	check(cfg.DB.Driver == "mysql" || cfg.DB.Driver == "sqlite3", "db.driver must be mysql or sqlite3, got %q", cfg.DB.Driver)
+	if cfg.DB.Driver == "sqlite3" {
+		check(cfg.DB.SQLitePath != "", "db.sqlite_path must not be empty")
+	}
+	check(cfg.DB.MaxOpenConns > 0, "db.max_open_conns must be positive")
+	check(cfg.DB.MaxIdleConns >= 0, "db.max_idle_conns must not be negative")
+	check(cfg.DB.ConnMaxLifetime >= 0, "db.conn_max_lifetime must not be negative")
+	check(cfg.DB.ConnectTimeout >= 0 && cfg.DB.ReadTimeout >= 0 && cfg.DB.WriteTimeout >= 0, "db timeouts must not be negative")
+	check(cfg.Mode == "development" || cfg.Mode == "production", "mode must be development or production, got %q", cfg.Mode)
+	check(cfg.Session.Store == "db" || cfg.Session.Store == "memory", "session.store must be db or memory, got %q", cfg.Session.Store)
+	check(cfg.Session.Secret != "", "session.secret must not be empty")
+	if cfg.Mode == "production" {
+		check(cfg.Session.Secret != defaultSessionSecret, "session.secret (RISUCON_SESSION_SECRETKEY) must be changed from the default in production mode")
+	}
+	check(cfg.Session.MaxAge > 0, "session.max_age must be positive")
+	check(cfg.Session.SameSite == "lax" || cfg.Session.SameSite == "strict" || cfg.Session.SameSite == "none", "session.same_site must be lax, strict or none, got %q", cfg.Session.SameSite)
+	if cfg.Session.SameSite == "none" {
+		check(cfg.Session.Secure, "session.same_site none requires session.secure")
+	}
+	check(cfg.Cache.TTL >= 0, "cache.ttl must not be negative")
+	check(cfg.Cache.MaxEntries >= 0, "cache.max_entries must not be negative")
+	check(cfg.Pagination.SubmissionsPerPage > 0, "pagination.submissions_per_page must be positive")
+	check(cfg.Pagination.UsersPerPage > 0, "pagination.users_per_page must be positive")
+	// invitation_code は VARCHAR(255)
+	check(cfg.Contest.InvitationCodeBytes >= 4 && cfg.Contest.InvitationCodeBytes <= 127, "contest.invitation_code_bytes must be between 4 and 127")
+	check(cfg.RateLimit.LoginInterval >= 0 && cfg.RateLimit.SubmitInterval >= 0 && cfg.RateLimit.SubmitTaskInterval >= 0, "rate_limit intervals must not be negative")
+	check(cfg.RateLimit.LoginBurst > 0 && cfg.RateLimit.SubmitBurst > 0, "rate_limit bursts must be positive")
+	check(cfg.Login.MaxFailures >= 0, "login.max_failures must not be negative")
+	if cfg.Login.MaxFailures > 0 {
+		check(cfg.Login.FailureWindow > 0 && cfg.Login.LockoutDuration > 0, "login.failure_window and login.lockout_duration must be positive")
+	}
+	check(cfg.Login.PasswordResetTTL > 0, "login.password_reset_ttl must be positive")
+	check(validRegistrationMode(cfg.Registration.Mode), "registration.mode must be open, closed, code or allowlist, got %q", cfg.Registration.Mode)
+	if cfg.Registration.Mode == registrationModeCode {
+		check(cfg.Registration.Code != "", "registration.code is required when registration.mode is code")
+	}
+	check(cfg.Log.Format == "json" || cfg.Log.Format == "ltsv", "log.format must be json or ltsv, got %q", cfg.Log.Format)
+	return errors.Join(errs...)
+}
+
+// 表示用。パスワードやシークレットは伏せる
+func (cfg Config) String() string {
+	if cfg.DB.Password != "" {
+		cfg.DB.Password = "********"
+	}
+	if cfg.Session.Secret != "" {
+		cfg.Session.Secret = "********"
+	}
+	if cfg.Registration.Code != "" {
+		cfg.Registration.Code = "********"
+	}
+	b, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err.Error()
+	}
+	return string(b)
+}
+
+// コマンドライン引数を読んで設定を組み立てる。残りの引数 (サブコマンド) と --print-config が指定されたかを返す
+func loadConfig(args []string) (Config, []string, bool, error) {
+	cfg := defaultConfig()
+
+	fs := flag.NewFlagSet("risucontest", flag.ContinueOnError)
+	configPath := fs.String("config", getEnv("RISUCON_CONFIG", ""), "path to a YAML config file")
+	printConfig := fs.Bool("print-config", false, "print the effective config and exit")
+	listenAddr := fs.String("listen", "", "listen address (overrides server.listen_addr)")
+	staticPath := fs.String("static-path", "", "frontend directory (overrides server.static_path)")
+	dbDriver := fs.String("db-driver", "", "mysql or sqlite3 (overrides db.driver)")
+	allowReset := fs.Bool("allow-reset", false, "accept reset operations over HTTP (overrides server.allow_reset)")
+	if err := fs.Parse(args); err != nil {
+		return Config{}, nil, false, err
+	}
+
+	if *configPath != "" {
+		if err := cfg.loadFile(*configPath); err != nil {
+			return Config{}, nil, false, err
+		}
+	}
+	if err := cfg.loadEnv(); err != nil {
+		return Config{}, nil, false, err
+	}
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "listen":
+			cfg.Server.ListenAddr = *listenAddr
+		case "static-path":
+			cfg.Server.StaticPath = *staticPath
+		case "db-driver":
+			cfg.DB.Driver = *dbDriver
+		case "allow-reset":
+			cfg.Server.AllowReset = *allowReset
+		}
+	})
+
+	if err := cfg.validate(); err != nil {
+		return Config{}, nil, false, fmt.Errorf("invalid config: %w", err)
+	}
+	return cfg, fs.Args(), *printConfig, nil
+}
@@ -2,29 +2,17 @@ package main
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
-	"strings"
-	"sync"
 	"time"
 
-	"github.com/labstack/echo-contrib/session"
 	"github.com/labstack/echo/v4"
 )
 
-var (
-	// Subtask は update がないのでキャッシュしておく
-	// メモ: initializeHandler でキャッシュを消すのを忘れずに
-	subtaskcache = sync.Map{}
-
-	standingssubcache       = sync.Map{}
-	standingssubexistscache = sync.Map{}
-	usercache               = sync.Map{}
-	subtaskmaxscorecache    = sync.Map{}
-)
-
 type Task struct {
 	ID              int    `db:"id"`
 	Name            string `db:"name"`
@@ -50,10 +38,11 @@ type Submission struct {
 	ID          int       `db:"id"`
 	TaskID      int       `db:"task_id"`
 	UserID      int       `db:"user_id"`
-	SubmittedAt time.Time `db:"submitted_at"`
+	SubmittedAt time.Time `db:"submitted_at"` // クライアントが送ってきた時刻
 	Answer      string    `db:"answer"`
 	SubTaskID   int       `db:"subtask_id"`
 	Score       int       `db:"score"`
+	ReceivedAt  time.Time `db:"received_at"` // サーバーが受け取った時刻
 }
 
 type TaskAbstract struct {
@@ -65,65 +54,105 @@ type TaskAbstract struct {
 	SubmissionCount int    `json:"submission_count,omitempty"`
 }
 
-func gettaskabstarcts(ctx context.Context, c echo.Context) ([]TaskAbstract, error) {
-	tasks := []Task{}
-	if err := dbConn.SelectContext(ctx, &tasks, "SELECT * FROM tasks ORDER BY name"); err != nil {
+// キャッシュを見てからサブタスクを取得する
+func (s *Server) getSubtasks(ctx context.Context, taskID int) ([]Subtask, error) {
+	if subtasks, ok := s.cache.Subtasks.Get(taskID); ok {
+		// データがキャッシュされているので、それを読み込む
+		return subtasks, nil
+	}
+	subtasks, err := s.store.Tasks().ListSubtasks(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	// キャッシュにデータを保存
+	s.cache.Subtasks.Set(taskID, subtasks)
+	return subtasks, nil
+}
+
+func (s *Server) getSubtaskMaxScore(ctx context.Context, subtaskID int) (int, error) {
+	if msc, ok := s.cache.SubtaskMaxScore.Get(subtaskID); ok {
+		return msc, nil
+	}
+	maxscore, err := s.store.Tasks().SubtaskMaxScore(ctx, subtaskID)
+	if err != nil {
+		return 0, err
+	}
+	s.cache.SubtaskMaxScore.Set(subtaskID, maxscore)
+	return maxscore, nil
+}
+
+func (s *Server) getTeamTaskScore(ctx context.Context, taskID int, team Team) (int, error) {
+	if sc, ok := s.cache.TeamTaskScore.Get(teamTaskKey{team.ID, taskID}); ok {
+		return sc, nil
+	}
+	score, err := s.store.Submissions().TeamTaskScore(ctx, taskID, team)
+	if err != nil {
+		return 0, err
+	}
+	s.cache.TeamTaskScore.Set(teamTaskKey{team.ID, taskID}, score)
+	return score, nil
+}
+
+// ログインしていてチームに所属していれば、そのチームを返す
+// 管理者は ?view_as=<ユーザー名> を付けると、そのユーザーから見える内容を確認できる (GET のみなので読み取り専用)
+func (s *Server) getLoginTeam(c echo.Context) (Team, bool, error) {
+	if err := verifyUserSession(c); err != nil {
+		return Team{}, false, nil
+	}
+	ctx := c.Request().Context()
+	username, _ := loginUserName(c)
+	if viewAs := c.QueryParam("view_as"); viewAs != "" {
+		if err := verifyAdminSession(c); err != nil {
+			return Team{}, false, err
+		}
+		username = viewAs
+	}
+	user, err := s.store.Users().GetByName(ctx, username)
+	if errors.Is(err, ErrNotFound) {
+		return Team{}, false, newAPIError(http.StatusNotFound, ErrCodeUserNotFound, "user not found")
+	} else if err != nil {
+		return Team{}, false, err
+	}
+	team, err := s.store.Teams().GetByMember(ctx, user.ID)
+	if errors.Is(err, ErrNotFound) {
+		return Team{}, false, nil
+	} else if err != nil {
+		return Team{}, false, err
+	}
+	setLogTeam(c, team.Name)
+	return team, true, nil
+}
+
+func (s *Server) gettaskabstarcts(ctx context.Context, c echo.Context) ([]TaskAbstract, error) {
+	tasks, err := s.store.Tasks().List(ctx)
+	if err != nil {
+		return []TaskAbstract{}, err
+	}
+	team, inteam, err := s.getLoginTeam(c)
+	if err != nil {
 		return []TaskAbstract{}, err
 	}
 	res := []TaskAbstract{}
 	for _, task := range tasks {
 		maxscore := 0
-		subtasks := []Subtask{}
-		if s, ok := subtaskcache.Load(task.ID); ok {
-			subtasks = s.([]Subtask)
-		} else {
-			if err := dbConn.SelectContext(ctx, &subtasks, "SELECT * FROM subtasks WHERE task_id = ?", task.ID); err != nil {
-				return []TaskAbstract{}, err
-			}
-			subtaskcache.Store(task.ID, subtasks)
+		subtasks, err := s.getSubtasks(ctx, task.ID)
+		if err != nil {
+			return []TaskAbstract{}, err
 		}
 		for _, subtask := range subtasks {
-			maxscore_for_subtask := 0
-			if msc, ok := subtaskmaxscorecache.Load(subtask.ID); ok {
-				maxscore_for_subtask = msc.(int)
-			} else {
-				if err := dbConn.GetContext(ctx, &maxscore_for_subtask, "SELECT MAX(score) FROM answers WHERE subtask_id = ?", subtask.ID); err != nil {
-					return []TaskAbstract{}, err
-				}
-				subtaskmaxscorecache.Store(subtask.ID, maxscore_for_subtask)
+			maxscore_for_subtask, err := s.getSubtaskMaxScore(ctx, subtask.ID)
+			if err != nil {
+				return []TaskAbstract{}, err
 			}
 			maxscore += maxscore_for_subtask
 		}
 		submissioncount := 0
 		score := 0
-		if err := verifyUserSession(c); err == nil {
-			sess, _ := session.Get(defaultSessionIDKey, c)
-			username, _ := sess.Values[defaultSessionUserNameKey].(string)
-			user := User{}
-			if err := dbConn.GetContext(c.Request().Context(), &user, "SELECT * FROM users WHERE name = ?", username); err != nil {
+		if inteam {
+			if submissioncount, err = s.store.Submissions().CountByTeam(ctx, task.ID, team); err != nil {
 				return []TaskAbstract{}, err
 			}
-			team := Team{}
-			err := dbConn.GetContext(c.Request().Context(), &team, "SELECT * FROM teams WHERE leader_id = ? OR member1_id = ? OR member2_id = ?", user.ID, user.ID, user.ID)
-			if err == nil {
-				err := dbConn.GetContext(c.Request().Context(), &submissioncount, "SELECT COUNT(*) FROM submissions WHERE task_id = ? AND user_id IN (?,?,?)", task.ID, team.LeaderID, team.Member1ID, team.Member2ID)
-				if err != nil {
-					return []TaskAbstract{}, err
-				}
-
-				if sc, ok := standingssubcache.Load(team.ID*10000 + task.ID); ok {
-					score = sc.(int)
-				} else {
-					sc := []int{}
-					if err := dbConn.SelectContext(ctx, &sc, "SELECT MAX(score) FROM submissions WHERE task_id = ? AND user_id IN (?,?,?) GROUP BY subtask_id;", task.ID, team.LeaderID, team.Member1ID, team.Member2ID); err != nil {
-						return []TaskAbstract{}, err
-					}
-					for _, s := range sc {
-						score += s
-					}
-					standingssubcache.Store(team.ID*10000+task.ID, score)
-				}
-			} else if err != sql.ErrNoRows {
+			if score, err = s.getTeamTaskScore(ctx, task.ID, team); err != nil {
 				return []TaskAbstract{}, err
 			}
 		}
@@ -141,12 +170,12 @@ func gettaskabstarcts(ctx context.Context, c echo.Context) ([]TaskAbstract, erro
 }
 
 // GET /api/tasks
-func getTasksHandler(c echo.Context) error {
+func (s *Server) getTasksHandler(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	taskabstarcts, err := gettaskabstarcts(ctx, c)
+	taskabstarcts, err := s.gettaskabstarcts(ctx, c)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get taskabstarcts: "+err.Error())
+		return asAPIError("failed to get taskabstarcts", err)
 	}
 
 	return c.JSON(http.StatusOK, taskabstarcts)
@@ -156,6 +185,8 @@ type TeamsStandingsSub struct {
 	TaskName     string `json:"task_name"`
 	HasSubmitted bool   `json:"has_submitted"`
 	Score        int    `json:"score"`
+	// このチームが最初に満点を取ったサブタスクの name
+	FirstSolves []string `json:"first_solves,omitempty"`
 }
 type TeamsStandings struct {
 	Rank               int                 `json:"rank"`
@@ -169,40 +200,27 @@ type TeamsStandings struct {
 	Member2DisplayName string              `json:"member2_display_name,omitempty"`
 	ScoringData        []TeamsStandingsSub `json:"scoring_data"`
 	TotalScore         int                 `json:"total_score"`
+	// 順位を付けないチーム (rank は 0)。失格のチームは一番下に並べる
+	Unofficial   bool `json:"unofficial,omitempty"`
+	Disqualified bool `json:"disqualified,omitempty"`
 }
 type Standings struct {
 	TasksData     []TaskAbstract   `json:"tasks_data"`
 	StandingsData []TeamsStandings `json:"standings_data"`
 }
 
-func getstandings(ctx context.Context) (Standings, error) {
+func (s *Server) getstandings(ctx context.Context) (Standings, error) {
 	standings := Standings{}
 
-	tasks := []Task{}
-	if err := dbConn.SelectContext(ctx, &tasks, "SELECT * FROM tasks ORDER BY name"); err != nil {
+	tasks, err := s.store.Tasks().List(ctx)
+	if err != nil {
 		return Standings{}, err
 	}
 	for _, task := range tasks {
-		/*subtasks := []Subtask{}
-		if err := dbConn.SelectContext(ctx, &subtasks, "SELECT * FROM subtasks WHERE task_id = ?", task.ID); err != nil {
-			return Standings{}, err
-		}
-		maxscore := 0
-		for _, subtask := range subtasks {
-			subtaskmaxscore := 0
-			if err := dbConn.GetContext(ctx, &subtaskmaxscore, "SELECT MAX(score) FROM answers WHERE subtask_id = ?", subtask.ID); err != nil {
-				return Standings{}, err
-			}
-			maxscore += subtaskmaxscore
-		}*/
-		maxscore := 0
-		sc := []int{}
-		if err := dbConn.SelectContext(ctx, &sc, "SELECT MAX(score) FROM answers WHERE task_id = ? GROUP BY subtask_id", task.ID); err != nil {
+		maxscore, err := s.store.Tasks().TaskMaxScore(ctx, task.ID)
+		if err != nil {
 			return Standings{}, err
 		}
-		for _, s := range sc {
-			maxscore += s
-		}
 
 		standings.TasksData = append(standings.TasksData, TaskAbstract{
 			Name:        task.Name,
@@ -211,50 +229,56 @@ func getstandings(ctx context.Context) (Standings, error) {
 		})
 	}
 
-	teams := []Team{}
-	if err := dbConn.SelectContext(ctx, &teams, "SELECT * FROM teams ORDER BY name"); err != nil {
+	// (チーム, タスク) ごとの first blood
+	firstsolves, err := s.store.FirstSolves().List(ctx, 0)
+	if err != nil {
+		return Standings{}, err
+	}
+	firstsolved := map[teamTaskKey][]string{}
+	for _, fs := range firstsolves {
+		subtasks, err := s.getSubtasks(ctx, fs.TaskID)
+		if err != nil {
+			return Standings{}, err
+		}
+		if i := slices.IndexFunc(subtasks, func(st Subtask) bool { return st.ID == fs.SubtaskID }); i >= 0 {
+			key := teamTaskKey{fs.TeamID, fs.TaskID}
+			firstsolved[key] = append(firstsolved[key], subtasks[i].Name)
+		}
+	}
+
+	teams, err := s.store.Teams().List(ctx)
+	if err != nil {
 		return Standings{}, err
 	}
 	for _, team := range teams {
+		if team.Status == teamStatusHidden {
+			continue
+		}
 		teamstandings := TeamsStandings{}
 		teamstandings.TeamName = team.Name
 		teamstandings.TeamDisplayName = team.DisplayName
 		teamstandings.TotalScore = 0
+		teamstandings.Unofficial = team.Status == teamStatusUnofficial
+		teamstandings.Disqualified = team.Status == teamStatusDisqualified
 
-		leader := User{}
-		if u, ok := usercache.Load(team.LeaderID); ok {
-			leader = u.(User)
-		} else {
-			if err := dbConn.GetContext(ctx, &leader, "SELECT * FROM users WHERE id = ?", team.LeaderID); err != nil {
-				return Standings{}, err
-			}
-			usercache.Store(team.LeaderID, leader)
+		leader, err := s.getUserByID(ctx, team.LeaderID)
+		if err != nil {
+			return Standings{}, err
 		}
-
 		teamstandings.LeaderName = leader.Name
 		teamstandings.LeaderDisplayName = leader.DisplayName
 		if team.Member1ID != nulluserid {
-			member1 := User{}
-			if u, ok := usercache.Load(team.Member1ID); ok {
-				member1 = u.(User)
-			} else {
-				if err := dbConn.GetContext(ctx, &member1, "SELECT * FROM users WHERE id = ?", team.Member1ID); err != nil {
-					return Standings{}, err
-				}
-				usercache.Store(team.Member1ID, member1)
+			member1, err := s.getUserByID(ctx, team.Member1ID)
+			if err != nil {
+				return Standings{}, err
 			}
 			teamstandings.Member1Name = member1.Name
 			teamstandings.Member1DisplayName = member1.DisplayName
 		}
 		if team.Member2ID != nulluserid {
-			member2 := User{}
-			if u, ok := usercache.Load(team.Member2ID); ok {
-				member2 = u.(User)
-			} else {
-				if err := dbConn.GetContext(ctx, &member2, "SELECT * FROM users WHERE id = ?", team.Member2ID); err != nil {
-					return Standings{}, err
-				}
-				usercache.Store(team.Member2ID, member2)
+			member2, err := s.getUserByID(ctx, team.Member2ID)
+			if err != nil {
+				return Standings{}, err
 			}
 			teamstandings.Member2Name = member2.Name
 			teamstandings.Member2DisplayName = member2.DisplayName
@@ -266,40 +290,23 @@ func getstandings(ctx context.Context) (Standings, error) {
 			taskscoringdata.TaskName = task.Name
 			taskscoringdata.HasSubmitted = false
 			taskscoringdata.Score = 0
+			taskscoringdata.FirstSolves = firstsolved[teamTaskKey{team.ID, task.ID}]
 
-			subtasks := []Subtask{}
-			if s, ok := subtaskcache.Load(task.ID); ok {
-				subtasks = s.([]Subtask)
+			if b, ok := s.cache.TeamTaskSubmitted.Get(teamTaskKey{team.ID, task.ID}); ok {
+				taskscoringdata.HasSubmitted = b
 			} else {
-				if err := dbConn.SelectContext(ctx, &subtasks, "SELECT * FROM subtasks WHERE task_id = ?", task.ID); err != nil {
-					return Standings{}, err
-				}
-				subtaskcache.Store(task.ID, subtasks)
-			}
-			if b, ok := standingssubexistscache.Load(team.ID*10000 + task.ID); ok {
-				taskscoringdata.HasSubmitted = b.(bool)
-			} else {
-				submissioncount := 0
-				if err := dbConn.GetContext(ctx, &submissioncount, "SELECT COUNT(*) FROM submissions WHERE task_id = ? AND user_id IN (?,?,?) LIMIT 1", task.ID, team.LeaderID, team.Member1ID, team.Member2ID); err != nil {
+				submissioncount, err := s.store.Submissions().CountByTeam(ctx, task.ID, team)
+				if err != nil {
 					return Standings{}, err
 				}
 				if submissioncount > 0 {
 					taskscoringdata.HasSubmitted = true
 				}
-				standingssubexistscache.Store(team.ID*10000+task.ID, taskscoringdata.HasSubmitted)
+				s.cache.TeamTaskSubmitted.Set(teamTaskKey{team.ID, task.ID}, taskscoringdata.HasSubmitted)
 			}
 
-			if sc, ok := standingssubcache.Load(team.ID*10000 + task.ID); ok {
-				taskscoringdata.Score = sc.(int)
-			} else {
-				sc := []int{}
-				if err := dbConn.SelectContext(ctx, &sc, "SELECT MAX(score) FROM submissions WHERE task_id = ? AND user_id IN (?,?,?) GROUP BY subtask_id;", task.ID, team.LeaderID, team.Member1ID, team.Member2ID); err != nil {
-					return Standings{}, err
-				}
-				for _, s := range sc {
-					taskscoringdata.Score += s
-				}
-				standingssubcache.Store(team.ID*10000+task.ID, taskscoringdata.Score)
+			if taskscoringdata.Score, err = s.getTeamTaskScore(ctx, task.ID, team); err != nil {
+				return Standings{}, err
 			}
 
 			scoringdata = append(scoringdata, taskscoringdata)
@@ -309,20 +316,25 @@ func getstandings(ctx context.Context) (Standings, error) {
 		standings.StandingsData = append(standings.StandingsData, teamstandings)
 	}
 
-	// sort
+	// sort (失格のチームは点数に関係なく後ろ)
 	for i := 0; i < len(standings.StandingsData); i++ {
 		for j := i + 1; j < len(standings.StandingsData); j++ {
-			if (standings.StandingsData[i].TotalScore < standings.StandingsData[j].TotalScore) || (standings.StandingsData[i].TotalScore == standings.StandingsData[j].TotalScore && standings.StandingsData[i].TeamName > standings.StandingsData[j].TeamName) {
-				tmp := standings.StandingsData[i]
-				standings.StandingsData[i] = standings.StandingsData[j]
-				standings.StandingsData[j] = tmp
+			a, b := standings.StandingsData[i], standings.StandingsData[j]
+			if (a.Disqualified && !b.Disqualified) || (a.Disqualified == b.Disqualified && (a.TotalScore < b.TotalScore || (a.TotalScore == b.TotalScore && a.TeamName > b.TeamName))) {
+				standings.StandingsData[i] = b
+				standings.StandingsData[j] = a
 			}
 		}
 	}
+	// 順位は正式参加のチームの中だけで数える
+	ranked := func(t TeamsStandings) bool { return !t.Unofficial && !t.Disqualified }
 	for i := 0; i < len(standings.StandingsData); i++ {
+		if !ranked(standings.StandingsData[i]) {
+			continue
+		}
 		standings.StandingsData[i].Rank = 1
 		for j := 0; j < len(standings.StandingsData); j++ {
-			if standings.StandingsData[i].TotalScore < standings.StandingsData[j].TotalScore {
+			if ranked(standings.StandingsData[j]) && standings.StandingsData[i].TotalScore < standings.StandingsData[j].TotalScore {
 				standings.StandingsData[i].Rank++
 			}
 		}
@@ -332,12 +344,12 @@ func getstandings(ctx context.Context) (Standings, error) {
 }
 
 // GET /api/stanings
-func getStandingsHandler(c echo.Context) error {
+func (s *Server) getStandingsHandler(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	standings, err := getstandings(ctx)
+	standings, err := s.getstandings(ctx)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get standings: "+err.Error())
+		return internalError("failed to get standings", err)
 	}
 
 	return c.JSON(http.StatusOK, standings)
@@ -349,6 +361,10 @@ type SubtaskDetail struct {
 	Statement   string `json:"statement"`
 	MaxScore    int    `json:"max_score"`
 	Score       int    `json:"score"`
+	// 最初に満点を取ったチーム。まだいなければ空
+	FirstSolveTeamName        string `json:"first_solve_team_name,omitempty"`
+	FirstSolveTeamDisplayName string `json:"first_solve_team_display_name,omitempty"`
+	FirstSolvedAt             int64  `json:"first_solved_at,omitempty"`
 }
 type TaskDetail struct {
 	Name            string          `json:"name"`
@@ -362,31 +378,20 @@ type TaskDetail struct {
 }
 
 // GET /api/tasks/:taskname
-func getTaskHandler(c echo.Context) error {
+func (s *Server) getTaskHandler(c echo.Context) error {
+	ctx := c.Request().Context()
 	taskname := c.Param("taskname")
 
-	task := Task{}
-
-	err := dbConn.GetContext(c.Request().Context(), &task, "SELECT * FROM tasks WHERE name = ?", taskname)
-
-	if err == sql.ErrNoRows {
-		return echo.NewHTTPError(http.StatusNotFound, "task not found")
+	task, err := s.store.Tasks().GetByName(ctx, taskname)
+	if errors.Is(err, ErrNotFound) {
+		return newAPIError(http.StatusNotFound, ErrCodeTaskNotFound, "task not found")
 	} else if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get task: "+err.Error())
+		return internalError("failed to get task", err)
 	}
 
-	subtasks := []Subtask{}
-
-	if cache_data, ok := subtaskcache.Load(task.ID); ok {
-		// データがキャッシュされているので、それを読み込む
-		subtasks = cache_data.([]Subtask)
-	} else {
-		if err := dbConn.SelectContext(c.Request().Context(), &subtasks, "SELECT * FROM subtasks WHERE task_id = ?", task.ID); err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "failed to get subtasks: "+err.Error())
-		}
-
-		// キャッシュにデータを保存
-		subtaskcache.Store(task.ID, subtasks)
+	subtasks, err := s.getSubtasks(ctx, task.ID)
+	if err != nil {
+		return internalError("failed to get subtasks", err)
 	}
 
 	res := TaskDetail{
@@ -406,43 +411,49 @@ func getTaskHandler(c echo.Context) error {
 			DisplayName: subtask.DisplayName,
 			Statement:   subtask.Statement,
 		}
-		if msc, ok := subtaskmaxscorecache.Load(subtask.ID); ok {
-			subtaskdetail.MaxScore = msc.(int)
-		} else {
-			if err := dbConn.GetContext(c.Request().Context(), &subtaskdetail.MaxScore, "SELECT MAX(score) FROM answers WHERE subtask_id = ?", subtask.ID); err != nil {
-				return echo.NewHTTPError(http.StatusInternalServerError, "failed to get subtask score: "+err.Error())
-			}
-			subtaskmaxscorecache.Store(subtask.ID, subtaskdetail.MaxScore)
+		if subtaskdetail.MaxScore, err = s.getSubtaskMaxScore(ctx, subtask.ID); err != nil {
+			return internalError("failed to get subtask score", err)
 		}
 		res.Subtasks = append(res.Subtasks, subtaskdetail)
 		res.MaxScore += subtaskdetail.MaxScore
 	}
 
-	if err := verifyUserSession(c); err == nil {
-		sess, _ := session.Get(defaultSessionIDKey, c)
-		username, _ := sess.Values[defaultSessionUserNameKey].(string)
-		user := User{}
-		if err := dbConn.GetContext(c.Request().Context(), &user, "SELECT * FROM users WHERE name = ?", username); err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "failed to get user: "+err.Error())
+	firstsolves, err := s.store.FirstSolves().List(ctx, task.ID)
+	if err != nil {
+		return internalError("failed to get first solves", err)
+	}
+	for _, fs := range firstsolves {
+		i := slices.IndexFunc(subtasks, func(st Subtask) bool { return st.ID == fs.SubtaskID })
+		if i < 0 {
+			continue
 		}
-		team := Team{}
-		err := dbConn.GetContext(c.Request().Context(), &team, "SELECT * FROM teams WHERE leader_id = ? OR member1_id = ? OR member2_id = ?", user.ID, user.ID, user.ID)
-		if err == nil {
-			err := dbConn.GetContext(c.Request().Context(), &res.SubmissionCount, "SELECT COUNT(*) FROM submissions WHERE task_id = ? AND user_id IN (?,?,?)", task.ID, team.LeaderID, team.Member1ID, team.Member2ID)
-			if err != nil {
-				return echo.NewHTTPError(http.StatusInternalServerError, "failed to get submission count: "+err.Error())
-			}
+		team, err := s.store.Teams().GetByID(ctx, fs.TeamID)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		} else if err != nil {
+			return internalError("failed to get team", err)
+		}
+		res.Subtasks[i].FirstSolveTeamName = team.Name
+		res.Subtasks[i].FirstSolveTeamDisplayName = team.DisplayName
+		res.Subtasks[i].FirstSolvedAt = fs.SolvedAt.Unix()
+	}
 
-			for i, subtask := range subtasks {
-				subtaskscore := 0
-				if err := dbConn.GetContext(c.Request().Context(), &subtaskscore, "SELECT COALESCE(MAX(score),0) FROM submissions WHERE subtask_id = ? AND user_id IN (?,?,?)", subtask.ID, team.LeaderID, team.Member1ID, team.Member2ID); err != nil {
-					return echo.NewHTTPError(http.StatusInternalServerError, "failed to get subtask score: "+err.Error())
-				}
-				res.Subtasks[i].Score = subtaskscore
-				res.Score += subtaskscore
+	team, inteam, err := s.getLoginTeam(c)
+	if err != nil {
+		return asAPIError("failed to get team", err)
+	}
+	if inteam {
+		if res.SubmissionCount, err = s.store.Submissions().CountByTeam(ctx, task.ID, team); err != nil {
+			return internalError("failed to get submission count", err)
+		}
+
+		for i, subtask := range subtasks {
+			subtaskscore, err := s.store.Submissions().TeamSubtaskScore(ctx, subtask.ID, team)
+			if err != nil {
+				return internalError("failed to get subtask score", err)
 			}
-		} else if err != sql.ErrNoRows {
-			return echo.NewHTTPError(http.StatusInternalServerError, "failed to get team: "+err.Error())
+			res.Subtasks[i].Score = subtaskscore
+			res.Score += subtaskscore
 		}
 	}
 
@@ -465,7 +476,7 @@ type SubmitResponse struct {
 }
 
 // POST /api/submit
-func submitHandler(c echo.Context) error {
+func (s *Server) submitHandler(c echo.Context) error {
 	ctx := c.Request().Context()
 	defer c.Request().Body.Close()
 
@@ -473,106 +484,127 @@ func submitHandler(c echo.Context) error {
 		return err
 	}
 
-	sess, _ := session.Get(defaultSessionIDKey, c)
-	username, _ := sess.Values[defaultSessionUserNameKey].(string)
+	username, _ := loginUserName(c)
 
-	tx, err := dbConn.BeginTxx(c.Request().Context(), nil)
-	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to begin transaction: "+err.Error())
-	}
-	defer tx.Rollback()
-
-	user := User{}
-	if err := tx.GetContext(c.Request().Context(), &user, "SELECT * FROM users WHERE name = ?", username); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get user: "+err.Error())
-	}
-
-	team := Team{}
-	err = tx.GetContext(c.Request().Context(), &team, "SELECT * FROM teams WHERE leader_id = ? OR member1_id = ? OR member2_id = ?", user.ID, user.ID, user.ID)
-	if err == sql.ErrNoRows {
-		return echo.NewHTTPError(http.StatusBadRequest, "you have not joined team")
-	} else if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get team: "+err.Error())
-	}
+	res := SubmitResponse{}
+	submittedteam, submittedtask := 0, 0
+	// 提出が保存できなかったときは、使ったレート制限のトークンを返す
+	teamKey, taskKey := "", ""
+	err := s.store.WithTx(ctx, func(st Store) error {
+		user, err := st.Users().GetByName(ctx, username)
+		if err != nil {
+			return internalError("failed to get user", err)
+		}
+		if user.Disabled {
+			return newAPIError(http.StatusForbidden, ErrCodeAccountDisabled, "account is disabled")
+		}
 
-	req := SubmitRequest{}
-	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "failed to decode the request body as json")
-	}
+		team, err := st.Teams().GetByMember(ctx, user.ID)
+		if errors.Is(err, ErrNotFound) {
+			return newAPIError(http.StatusBadRequest, ErrCodeNotInTeam, "you have not joined team")
+		} else if err != nil {
+			return internalError("failed to get team", err)
+		}
+		setLogTeam(c, team.Name)
 
-	task := Task{}
-	err = tx.GetContext(c.Request().Context(), &task, "SELECT * FROM tasks WHERE name = ?", req.TaskName)
-	if err == sql.ErrNoRows {
-		return echo.NewHTTPError(http.StatusBadRequest, "task not found")
-	} else if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get task: "+err.Error())
-	}
+		req := SubmitRequest{}
+		if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+			return newAPIError(http.StatusBadRequest, ErrCodeInvalidRequest, "failed to decode the request body as json")
+		}
 
-	submissionscount := 0
-	if err := tx.GetContext(c.Request().Context(), &submissionscount, "SELECT COUNT(*) FROM submissions WHERE task_id = ? AND user_id IN (?,?,?)", task.ID, team.LeaderID, team.Member1ID, team.Member2ID); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get submissions count: "+err.Error())
-	}
+		task, err := st.Tasks().GetByName(ctx, req.TaskName)
+		if errors.Is(err, ErrNotFound) {
+			return newAPIError(http.StatusBadRequest, ErrCodeTaskNotFound, "task not found")
+		} else if err != nil {
+			return internalError("failed to get task", err)
+		}
 
-	if submissionscount >= task.SubmissionLimit {
-		return echo.NewHTTPError(http.StatusBadRequest, "submission limit exceeded")
-	}
+		submissionscount, err := st.Submissions().CountByTeam(ctx, task.ID, team)
+		if err != nil {
+			return internalError("failed to get submissions count", err)
+		}
 
-	res := SubmitResponse{}
+		if submissionscount >= task.SubmissionLimit {
+			return newAPIError(http.StatusBadRequest, ErrCodeSubmissionLimitExceeded, "submission limit exceeded")
+		}
+		// 不正なリクエストや提出回数の上限で弾いたものは数えない
+		if ok, retryAfter := s.limits.Submit.allow(strconv.Itoa(team.ID)); !ok {
+			return rateLimited(c, retryAfter)
+		}
+		teamKey = strconv.Itoa(team.ID)
+		if ok, retryAfter := s.limits.SubmitInterval.allow(fmt.Sprintf("%d:%d", team.ID, task.ID)); !ok {
+			return rateLimited(c, retryAfter)
+		}
+		taskKey = fmt.Sprintf("%d:%d", team.ID, task.ID)
 
-	// デフォルトではこれを返す。答えが有効な場合は更新される。
-	res.IsScored = false
-	res.Score = 0
-	res.RemainingSubmissions = task.SubmissionLimit - submissionscount - 1
+		// デフォルトではこれを返す。答えが有効な場合は更新される。
+		res.IsScored = false
+		res.Score = 0
+		res.RemainingSubmissions = task.SubmissionLimit - submissionscount - 1
 
-	subtasks := []Subtask{}
-	if s, ok := subtaskcache.Load(task.ID); ok {
-		subtasks = s.([]Subtask)
-	} else {
-		if err := tx.SelectContext(c.Request().Context(), &subtasks, "SELECT * FROM subtasks WHERE task_id = ?", task.ID); err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "failed to get subtasks: "+err.Error())
-		}
-		subtaskcache.Store(task.ID, subtasks)
-	}
-	subtaskid := -1
-	for _, subtask := range subtasks {
-		answers := []Answer{}
-		if err := tx.SelectContext(c.Request().Context(), &answers, "SELECT * FROM answers WHERE subtask_id = ?", subtask.ID); err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "failed to get answers: "+err.Error())
-		}
-		// SubTaskMaxScore は事前に計算しておく
-		subtaskmaxscore := 0
-		for _, answer := range answers {
-			if subtaskmaxscore < answer.Score {
-				subtaskmaxscore = answer.Score
+		subtasks, ok := s.cache.Subtasks.Get(task.ID)
+		if !ok {
+			if subtasks, err = st.Tasks().ListSubtasks(ctx, task.ID); err != nil {
+				return internalError("failed to get subtasks", err)
 			}
+			s.cache.Subtasks.Set(task.ID, subtasks)
 		}
-		// 答えが有効な場合、スコアを更新する
-		for _, answer := range answers {
-			if answer.Answer == req.Answer {
-				res.IsScored = true
-				res.Score = answer.Score
-				res.SubtaskName = subtask.Name
-				res.SubTaskDisplayName = subtask.DisplayName
-				res.SubTaskMaxScore = subtaskmaxscore
-				subtaskid = subtask.ID
+		subtaskid := -1
+		for _, subtask := range subtasks {
+			answers, err := st.Tasks().ListAnswers(ctx, subtask.ID)
+			if err != nil {
+				return internalError("failed to get answers", err)
+			}
+			// SubTaskMaxScore は事前に計算しておく
+			subtaskmaxscore := 0
+			for _, answer := range answers {
+				if subtaskmaxscore < answer.Score {
+					subtaskmaxscore = answer.Score
+				}
+			}
+			// 答えが有効な場合、スコアを更新する
+			for _, answer := range answers {
+				if answer.Answer == req.Answer {
+					res.IsScored = true
+					res.Score = answer.Score
+					res.SubtaskName = subtask.Name
+					res.SubTaskDisplayName = subtask.DisplayName
+					res.SubTaskMaxScore = subtaskmaxscore
+					subtaskid = subtask.ID
+				}
 			}
 		}
-	}
 
-	timestamp := time.Unix(req.Timestamp, 0)
-
-	if _, err = tx.ExecContext(ctx, "INSERT INTO submissions (task_id, user_id, submitted_at, answer, subtask_id, score) VALUES (?, ?, ?, ?, ?, ?)", task.ID, user.ID, timestamp, req.Answer, subtaskid, res.Score); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to insert submission: "+err.Error())
-	}
-
-	standingssubexistscache.Store(team.ID*10000+task.ID, true)
-	if res.IsScored {
-		standingssubcache.Delete(team.ID*10000 + task.ID)
-	}
+		submission := Submission{
+			TaskID:      task.ID,
+			UserID:      user.ID,
+			SubmittedAt: time.Unix(req.Timestamp, 0),
+			Answer:      req.Answer,
+			SubTaskID:   subtaskid,
+			Score:       res.Score,
+			ReceivedAt:  dbNow(),
+		}
+		if err := st.Submissions().Create(ctx, submission); err != nil {
+			return internalError("failed to insert submission", err)
+		}
+		if err := recordFirstSolve(ctx, st, team, submission, res.SubTaskMaxScore); err != nil {
+			return internalError("failed to record first solve", err)
+		}
 
-	if err := tx.Commit(); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to commit transaction: "+err.Error())
+		submittedteam, submittedtask = team.ID, task.ID
+		return nil
+	})
+	if err != nil {
+		if teamKey != "" {
+			s.limits.Submit.refund(teamKey)
+		}
+		if taskKey != "" {
+			s.limits.SubmitInterval.refund(taskKey)
+		}
+		return asAPIError("failed to commit transaction", err)
 	}
+	s.cache.Publish(cacheEvent{Kind: cacheEventSubmissionCreated, TeamID: submittedteam, TaskID: submittedtask, Scored: res.IsScored})
+	s.metrics.observeSubmission(res.IsScored)
 
 	return c.JSON(http.StatusCreated, res)
 }
@@ -596,136 +628,111 @@ type submissionresponse struct {
 }
 
 // GET /api/submissions
-func getSubmissionsHandler(c echo.Context) error {
-	submissionsperpage := 20
+func (s *Server) getSubmissionsHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+	submissionsperpage := s.cfg.Pagination.SubmissionsPerPage
 	if err := verifyUserSession(c); err != nil {
 		return err
 	}
 
-	sess, _ := session.Get(defaultSessionIDKey, c)
-	username, _ := sess.Values[defaultSessionUserNameKey].(string)
+	username, _ := loginUserName(c)
 
-	user := User{}
-	if err := dbConn.GetContext(c.Request().Context(), &user, "SELECT * FROM users WHERE name = ?", username); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get user: "+err.Error())
+	user, err := s.store.Users().GetByName(ctx, username)
+	if err != nil {
+		return internalError("failed to get user", err)
 	}
 
 	team := Team{}
 	if username != "admin" {
-		err := dbConn.GetContext(c.Request().Context(), &team, "SELECT * FROM teams WHERE leader_id = ? OR member1_id = ? OR member2_id = ?", user.ID, user.ID, user.ID)
-		if err == sql.ErrNoRows {
-			return echo.NewHTTPError(http.StatusBadRequest, "you have not joined team")
+		team, err = s.store.Teams().GetByMember(ctx, user.ID)
+		if errors.Is(err, ErrNotFound) {
+			return newAPIError(http.StatusBadRequest, ErrCodeNotInTeam, "you have not joined team")
 		} else if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "failed to get team: "+err.Error())
+			return internalError("failed to get team", err)
 		}
+		setLogTeam(c, team.Name)
 	} else if c.QueryParam("team_name") != "" {
-		err := dbConn.GetContext(c.Request().Context(), &team, "SELECT * FROM teams WHERE name = ?", c.QueryParam("team_name"))
-		if err == sql.ErrNoRows {
-			return echo.NewHTTPError(http.StatusBadRequest, "team not found")
+		team, err = s.store.Teams().GetByName(ctx, c.QueryParam("team_name"))
+		if errors.Is(err, ErrNotFound) {
+			return newAPIError(http.StatusBadRequest, ErrCodeTeamNotFound, "team not found")
 		} else if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "failed to get team: "+err.Error())
+			return internalError("failed to get team", err)
 		}
 	}
 
-	conditions := make([]string, 0)
-	params := make([]interface{}, 0)
+	filter := SubmissionFilter{
+		AnswerContains: c.QueryParam("filter"),
+	}
 
 	if c.QueryParam("task_name") != "" {
-		task := Task{}
-		err := dbConn.GetContext(c.Request().Context(), &task, "SELECT * FROM tasks WHERE name = ?", c.QueryParam("task_name"))
-		if err == sql.ErrNoRows {
-			return echo.NewHTTPError(http.StatusBadRequest, "task not found")
+		task, err := s.store.Tasks().GetByName(ctx, c.QueryParam("task_name"))
+		if errors.Is(err, ErrNotFound) {
+			return newAPIError(http.StatusBadRequest, ErrCodeTaskNotFound, "task not found")
 		} else if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "failed to get task: "+err.Error())
+			return internalError("failed to get task", err)
 		}
-		conditions = append(conditions, "task_id = ?")
-		params = append(params, task.ID)
+		filter.TaskID = task.ID
 	}
 	if c.QueryParam("user_name") != "" {
-		user := User{}
-		err := dbConn.GetContext(c.Request().Context(), &user, "SELECT * FROM users WHERE name = ?", c.QueryParam("user_name"))
-		if err == sql.ErrNoRows {
-			return echo.NewHTTPError(http.StatusBadRequest, "user not found")
+		user, err := s.store.Users().GetByName(ctx, c.QueryParam("user_name"))
+		if errors.Is(err, ErrNotFound) {
+			return newAPIError(http.StatusBadRequest, ErrCodeUserNotFound, "user not found")
+		} else if err != nil {
+			return internalError("failed to get user", err)
 		}
-		conditions = append(conditions, "user_id = ?")
-		params = append(params, user.ID)
-	}
-	if c.QueryParam("filter") != "" {
-		conditions = append(conditions, "answer LIKE CONCAT('%', ?, '%')")
-		params = append(params, c.QueryParam("filter"))
+		filter.UserID = user.ID
 	}
 
 	if username != "admin" || c.QueryParam("team_name") != "" {
-		subconditions := "user_id = ?"
-		params = append(params, team.LeaderID)
+		filter.UserIDs = append(filter.UserIDs, team.LeaderID)
 		if team.Member1ID != nulluserid {
-			subconditions += " OR user_id = ?"
-			params = append(params, team.Member1ID)
+			filter.UserIDs = append(filter.UserIDs, team.Member1ID)
 		}
 		if team.Member2ID != nulluserid {
-			subconditions += " OR user_id = ?"
-			params = append(params, team.Member2ID)
+			filter.UserIDs = append(filter.UserIDs, team.Member2ID)
 		}
-		conditions = append(conditions, "("+subconditions+")")
 	}
 
-	submissions := []Submission{}
-	query := ""
-	if len(conditions) > 0 {
-		query = "SELECT * FROM submissions WHERE " + strings.Join(conditions, " AND ") + " ORDER BY submitted_at DESC"
-	} else {
-		query = "SELECT * FROM submissions ORDER BY submitted_at DESC"
-	}
-	if err := dbConn.SelectContext(c.Request().Context(), &submissions, query, params...); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get submissions: "+err.Error())
+	submissions, err := s.store.Submissions().List(ctx, filter)
+	if err != nil {
+		return internalError("failed to get submissions", err)
 	}
 
 	submissiondata := []SubmissionDetail{}
 	for _, submission := range submissions {
 		submissiondetail := SubmissionDetail{}
-		task := Task{}
-		if err := dbConn.GetContext(c.Request().Context(), &task, "SELECT * FROM tasks WHERE id = ?", submission.TaskID); err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "failed to get task: "+err.Error())
+		task, err := s.store.Tasks().GetByID(ctx, submission.TaskID)
+		if err != nil {
+			return internalError("failed to get task", err)
 		}
 		submissiondetail.TaskName = task.Name
 		submissiondetail.TaskDisplayName = task.DisplayName
 
-		answer := Answer{}
-		err := dbConn.GetContext(c.Request().Context(), &answer, "SELECT * FROM answers WHERE task_id = ? AND answer = ?", task.ID, submission.Answer)
-		if err == sql.ErrNoRows {
+		answer, err := s.store.Tasks().FindAnswer(ctx, task.ID, submission.Answer)
+		if errors.Is(err, ErrNotFound) {
 			submissiondetail.SubTaskName = ""
 			submissiondetail.SubTaskDisplayName = ""
 			submissiondetail.Score = 0
 			submissiondetail.SubTaskMaxScore = 0
 		} else if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "failed to get answer: "+err.Error())
+			return internalError("failed to get answer", err)
 		} else {
-			subtask := Subtask{}
-			if err := dbConn.GetContext(c.Request().Context(), &subtask, "SELECT * FROM subtasks WHERE id = ?", answer.SubtaskID); err != nil {
-				return echo.NewHTTPError(http.StatusInternalServerError, "failed to get subtask: "+err.Error())
+			subtask, err := s.store.Tasks().GetSubtask(ctx, answer.SubtaskID)
+			if err != nil {
+				return internalError("failed to get subtask", err)
 			}
 			submissiondetail.SubTaskName = subtask.Name
 			submissiondetail.SubTaskDisplayName = subtask.DisplayName
 			submissiondetail.Score = answer.Score
 
-			if msc, ok := subtaskmaxscorecache.Load(subtask.ID); ok {
-				submissiondetail.SubTaskMaxScore = msc.(int)
-			} else {
-				if err := dbConn.GetContext(c.Request().Context(), &submissiondetail.SubTaskMaxScore, "SELECT MAX(score) FROM answers WHERE subtask_id = ?", subtask.ID); err != nil {
-					return echo.NewHTTPError(http.StatusInternalServerError, "failed to get subtask score: "+err.Error())
-				}
-				subtaskmaxscorecache.Store(subtask.ID, submissiondetail.SubTaskMaxScore)
+			if submissiondetail.SubTaskMaxScore, err = s.getSubtaskMaxScore(ctx, subtask.ID); err != nil {
+				return internalError("failed to get subtask score", err)
 			}
 		}
 
-		user := User{}
-		if u, ok := usercache.Load(submission.UserID); ok {
-			user = u.(User)
-		} else {
-			if err := dbConn.GetContext(c.Request().Context(), &user, "SELECT * FROM users WHERE id = ?", submission.UserID); err != nil {
-				return echo.NewHTTPError(http.StatusInternalServerError, "failed to get user: "+err.Error())
-			}
-			usercache.Store(submission.UserID, user)
+		user, err := s.getUserByID(ctx, submission.UserID)
+		if err != nil {
+			return internalError("failed to get user", err)
 		}
 		submissiondetail.UserName = user.Name
 		submissiondetail.UserDisplayName = user.DisplayName
@@ -741,12 +748,12 @@ func getSubmissionsHandler(c echo.Context) error {
 	if c.QueryParam("page") != "" {
 		p, err := strconv.Atoi(c.QueryParam("page"))
 		if err != nil {
-			return echo.NewHTTPError(http.StatusBadRequest, "failed to parse page: "+err.Error())
+			return newAPIError(http.StatusBadRequest, ErrCodeInvalidPage, "failed to parse page")
 		}
 		page = p
 	}
 	if page < 1 {
-		return echo.NewHTTPError(http.StatusBadRequest, "page must be positive")
+		return newAPIError(http.StatusBadRequest, ErrCodeInvalidPage, "page must be positive")
 	}
 	start := (page - 1) * submissionsperpage
 	end := start + submissionsperpage
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// alice と bob がチーム alpha、carol はチームなし
+// タスク A (提出は 3 回まで) にサブタスク A1 (満点 100) と A2 (満点 30)
+func newSubmitStore() *fakeStore {
+	f := newFakeStore()
+	f.users = []User{
+		{ID: 1, Name: "alice", DisplayName: "Alice"},
+		{ID: 2, Name: "bob", DisplayName: "Bob"},
+		{ID: 3, Name: "carol", DisplayName: "Carol"},
+	}
+	f.teams = []Team{{ID: 1, Name: "alpha", DisplayName: "Alpha", LeaderID: 1, Member1ID: 2, Member2ID: nulluserid}}
+	f.tasks = []Task{{ID: 1, Name: "A", DisplayName: "Task A", SubmissionLimit: 3}}
+	f.subtasks = []Subtask{
+		{ID: 1, Name: "A1", DisplayName: "A-1", TaskID: 1},
+		{ID: 2, Name: "A2", DisplayName: "A-2", TaskID: 1},
+	}
+	f.answers = []Answer{
+		{ID: 1, TaskID: 1, SubtaskID: 1, Answer: "half", Score: 50},
+		{ID: 2, TaskID: 1, SubtaskID: 1, Answer: "full", Score: 100},
+		{ID: 3, TaskID: 1, SubtaskID: 2, Answer: "two", Score: 30},
+	}
+	return f
+}
+
+// 成功なら 201 と結果を、失敗なら APIError の status と code を返す
+func postSubmit(t *testing.T, s *Server, userName string, taskName string, answer string) (int, SubmitResponse, ErrorCode) {
+	t.Helper()
+	e := echo.New()
+	body := `{"task_name":"` + taskName + `","answer":"` + answer + `","timestamp":1700000000}`
+	req := httptest.NewRequest(http.MethodPost, "/api/submit", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	res := SubmitResponse{}
+	if err := withLogin(userName, s.submitHandler)(c); err != nil {
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) {
+			t.Fatalf("submit: %v", err)
+		}
+		return apiErr.Status, res, apiErr.Code
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return rec.Code, res, ""
+}
+
+func TestSubmitHandler(t *testing.T) {
+	f := newSubmitStore()
+	s := newTestServer(f)
+
+	status, res, code := postSubmit(t, s, "alice", "A", "full")
+	if status != http.StatusCreated || code != "" {
+		t.Fatalf("got %d %s, want 201", status, code)
+	}
+	want := SubmitResponse{IsScored: true, Score: 100, SubtaskName: "A1", SubTaskDisplayName: "A-1", SubTaskMaxScore: 100, RemainingSubmissions: 2}
+	if res != want {
+		t.Errorf("got %+v, want %+v", res, want)
+	}
+
+	// 送ってきた timestamp ではなく、受け取った時刻で記録する
+	if fs, ok := f.firstSolves[1]; !ok || fs.TeamID != 1 || fs.UserID != 1 || time.Since(fs.SolvedAt) > time.Minute {
+		t.Errorf("got first solve %+v, want team 1 user 1 solved just now", fs)
+	}
+
+	// 不正解でも提出の回数には数える
+	if _, res, _ := postSubmit(t, s, "bob", "A", "wrong"); res.IsScored || res.Score != 0 || res.RemainingSubmissions != 1 {
+		t.Errorf("got %+v, want an unscored submission with 1 remaining", res)
+	}
+	if _, res, _ := postSubmit(t, s, "alice", "A", "two"); res.Score != 30 || res.RemainingSubmissions != 0 {
+		t.Errorf("got %+v, want score 30 with 0 remaining", res)
+	}
+	if status, _, code := postSubmit(t, s, "bob", "A", "half"); status != http.StatusBadRequest || code != ErrCodeSubmissionLimitExceeded {
+		t.Errorf("got %d %s, want 400 %s", status, code, ErrCodeSubmissionLimitExceeded)
+	}
+
+	wantSubmissions := []struct{ userID, subtaskID, score int }{{1, 1, 100}, {2, -1, 0}, {1, 2, 30}}
+	if len(f.submissions) != len(wantSubmissions) {
+		t.Fatalf("got %d submissions, want %d", len(f.submissions), len(wantSubmissions))
+	}
+	for i, w := range wantSubmissions {
+		got := f.submissions[i]
+		if got.UserID != w.userID || got.SubTaskID != w.subtaskID || got.Score != w.score {
+			t.Errorf("submission %d: got %+v, want user %d subtask %d score %d", i, got, w.userID, w.subtaskID, w.score)
+		}
+	}
+}
+
+func TestSubmitHandlerRejects(t *testing.T) {
+	s := newTestServer(newSubmitStore())
+	if status, _, code := postSubmit(t, s, "carol", "A", "full"); status != http.StatusBadRequest || code != ErrCodeNotInTeam {
+		t.Errorf("got %d %s, want 400 %s", status, code, ErrCodeNotInTeam)
+	}
+	if status, _, code := postSubmit(t, s, "alice", "Z", "full"); status != http.StatusBadRequest || code != ErrCodeTaskNotFound {
+		t.Errorf("got %d %s, want 400 %s", status, code, ErrCodeTaskNotFound)
+	}
+}
+
+func TestGetStandings(t *testing.T) {
+	f := newFakeStore()
+	f.users = []User{
+		{ID: 11, Name: "dan", DisplayName: "Dan"},
+		{ID: 12, Name: "chris", DisplayName: "Chris"},
+		{ID: 13, Name: "cathy", DisplayName: "Cathy"},
+		{ID: 14, Name: "eve", DisplayName: "Eve"},
+	}
+	f.teams = []Team{
+		{ID: 11, Name: "delta", LeaderID: 11, Member1ID: nulluserid, Member2ID: nulluserid},
+		{ID: 12, Name: "charlie", LeaderID: 12, Member1ID: 13, Member2ID: nulluserid},
+		{ID: 13, Name: "echo", LeaderID: 14, Member1ID: nulluserid, Member2ID: nulluserid},
+	}
+	f.tasks = []Task{{ID: 11, Name: "X", SubmissionLimit: 10}, {ID: 12, Name: "Y", SubmissionLimit: 10}}
+	f.subtasks = []Subtask{{ID: 21, Name: "X1", TaskID: 11}, {ID: 22, Name: "Y1", TaskID: 12}}
+	f.answers = []Answer{{ID: 21, TaskID: 11, SubtaskID: 21, Answer: "x", Score: 60}, {ID: 22, TaskID: 12, SubtaskID: 22, Answer: "y", Score: 40}}
+	f.submissions = []Submission{
+		{ID: 1, TaskID: 11, UserID: 11, SubTaskID: 21, Score: 60},
+		{ID: 2, TaskID: 11, UserID: 13, SubTaskID: 21, Score: 60},
+		{ID: 3, TaskID: 12, UserID: 12, SubTaskID: -1, Score: 0},
+	}
+	s := newTestServer(f)
+
+	standings, err := s.getstandings(context.Background())
+	if err != nil {
+		t.Fatalf("getstandings: %v", err)
+	}
+	if len(standings.TasksData) != 2 || standings.TasksData[0].MaxScore != 60 || standings.TasksData[1].MaxScore != 40 {
+		t.Errorf("got tasks %+v, want max scores 60 and 40", standings.TasksData)
+	}
+	// 同点はチーム名の順で、順位は同じ
+	want := []struct {
+		team         string
+		rank         int
+		total        int
+		submittedToY bool
+	}{
+		{"charlie", 1, 60, true},
+		{"delta", 1, 60, false},
+		{"echo", 3, 0, false},
+	}
+	if len(standings.StandingsData) != len(want) {
+		t.Fatalf("got %d teams, want %d", len(standings.StandingsData), len(want))
+	}
+	for i, w := range want {
+		got := standings.StandingsData[i]
+		if got.TeamName != w.team || got.Rank != w.rank || got.TotalScore != w.total || got.ScoringData[1].HasSubmitted != w.submittedToY {
+			t.Errorf("row %d: got %s rank %d total %d submitted to Y %v, want %s rank %d total %d submitted to Y %v",
+				i, got.TeamName, got.Rank, got.TotalScore, got.ScoringData[1].HasSubmitted, w.team, w.rank, w.total, w.submittedToY)
+		}
+	}
+	if got := standings.StandingsData[0]; got.LeaderName != "chris" || got.Member1Name != "cathy" || got.Member2Name != "" {
+		t.Errorf("got members %s %s %s, want chris cathy", got.LeaderName, got.Member1Name, got.Member2Name)
+	}
+}
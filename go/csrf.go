@@ -0,0 +1,72 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/labstack/echo-contrib/session"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// セッションに入れておく CSRF トークン (synchronizer token)
+	defaultSessionCSRFTokenKey = "csrf_token"
+	csrfTokenHeader            = "X-CSRF-Token"
+)
+
+// クッキーでログインしているリクエストのうち、GET 以外のものにはセッションと同じ CSRF トークンを要求する
+// API トークンのリクエストはクッキーを使わないので対象外。ログインしていないリクエスト (ログイン・登録) も対象外
+func (s *Server) csrfProtection() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			switch c.Request().Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				return next(c)
+			}
+			if _, ok := c.Get(apiTokenUserKey).(string); ok {
+				return next(c)
+			}
+			sess, err := session.Get(defaultSessionIDKey, c)
+			if err != nil {
+				return internalError("failed to get session", err)
+			}
+			if username, _ := sess.Values[defaultSessionUserNameKey].(string); username == "" {
+				return next(c)
+			}
+			expected, _ := sess.Values[defaultSessionCSRFTokenKey].(string)
+			got := c.Request().Header.Get(csrfTokenHeader)
+			if expected == "" || subtle.ConstantTimeCompare([]byte(expected), []byte(got)) != 1 {
+				return newAPIError(http.StatusForbidden, ErrCodeInvalidCSRFToken, "missing or invalid "+csrfTokenHeader+" header")
+			}
+			return next(c)
+		}
+	}
+}
+
+type CSRFTokenResponse struct {
+	Token string `json:"token"`
+}
+
+// GET /api/csrf-token
+// フロントエンドはこれを X-CSRF-Token ヘッダーに付けて POST する。ログインするたびに変わる
+func (s *Server) getCSRFTokenHandler(c echo.Context) error {
+	if err := verifyUserSession(c); err != nil {
+		return err
+	}
+	sess, err := session.Get(defaultSessionIDKey, c)
+	if err != nil {
+		return internalError("failed to get session", err)
+	}
+	token, _ := sess.Values[defaultSessionCSRFTokenKey].(string)
+	if token == "" {
+		// CSRF トークンを入れるようになる前からあるセッション
+		if token, err = randomToken(); err != nil {
+			return internalError("failed to generate csrf token", err)
+		}
+		sess.Values[defaultSessionCSRFTokenKey] = token
+		if err := sess.Save(c.Request(), c.Response()); err != nil {
+			return internalError("failed to save session", err)
+		}
+	}
+	return c.JSON(http.StatusOK, CSRFTokenResponse{Token: token})
+}
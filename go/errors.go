@@ -0,0 +1,169 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// クライアントはメッセージではなくこのコードで判定する。一度公開したコードは変えないこと
+type ErrorCode string
+
+const (
+	ErrCodeInvalidRequest          ErrorCode = "INVALID_REQUEST"
+	ErrCodeValidationFailed        ErrorCode = "VALIDATION_FAILED"
+	ErrCodeNotLoggedIn             ErrorCode = "NOT_LOGGED_IN"
+	ErrCodeNotAdmin                ErrorCode = "NOT_ADMIN"
+	ErrCodeAuthenticationFailed    ErrorCode = "AUTHENTICATION_FAILED"
+	ErrCodeWrongPassword           ErrorCode = "WRONG_PASSWORD"
+	ErrCodeAccountLocked           ErrorCode = "ACCOUNT_LOCKED"
+	ErrCodeAccountDisabled         ErrorCode = "ACCOUNT_DISABLED"
+	ErrCodeRegistrationClosed      ErrorCode = "REGISTRATION_CLOSED"
+	ErrCodeInvalidRegistrationCode ErrorCode = "INVALID_REGISTRATION_CODE"
+	ErrCodeNotAllowlisted          ErrorCode = "NOT_ALLOWLISTED"
+	ErrCodeInvalidResetToken       ErrorCode = "INVALID_RESET_TOKEN"
+	ErrCodeUserNotFound            ErrorCode = "USER_NOT_FOUND"
+	ErrCodeUserAlreadyExists       ErrorCode = "USER_ALREADY_EXISTS"
+	ErrCodeTeamNotFound            ErrorCode = "TEAM_NOT_FOUND"
+	ErrCodeTeamAlreadyExists       ErrorCode = "TEAM_ALREADY_EXISTS"
+	ErrCodeTeamFull                ErrorCode = "TEAM_FULL"
+	ErrCodeAlreadyInTeam           ErrorCode = "ALREADY_IN_TEAM"
+	ErrCodeNotInTeam               ErrorCode = "NOT_IN_TEAM"
+	ErrCodeInvalidInvitationCode   ErrorCode = "INVALID_INVITATION_CODE"
+	ErrCodeTaskNotFound            ErrorCode = "TASK_NOT_FOUND"
+	ErrCodeTaskAlreadyExists       ErrorCode = "TASK_ALREADY_EXISTS"
+	ErrCodeSubtaskAlreadyExists    ErrorCode = "SUBTASK_ALREADY_EXISTS"
+	ErrCodeSubmissionLimitExceeded ErrorCode = "SUBMISSION_LIMIT_EXCEEDED"
+	ErrCodeRateLimited             ErrorCode = "RATE_LIMITED"
+	ErrCodeInvalidPage             ErrorCode = "INVALID_PAGE"
+	ErrCodeInvalidToken            ErrorCode = "INVALID_TOKEN"
+	ErrCodeInsufficientScope       ErrorCode = "INSUFFICIENT_SCOPE"
+	ErrCodeTokenNotFound           ErrorCode = "TOKEN_NOT_FOUND"
+	ErrCodeTooManyTokens           ErrorCode = "TOO_MANY_TOKENS"
+	ErrCodeInvalidCSRFToken        ErrorCode = "INVALID_CSRF_TOKEN"
+	ErrCodeSessionNotFound         ErrorCode = "SESSION_NOT_FOUND"
+	ErrCodeResetDisabled           ErrorCode = "RESET_DISABLED"
+	ErrCodeNotFound                ErrorCode = "NOT_FOUND"
+	ErrCodeMethodNotAllowed        ErrorCode = "METHOD_NOT_ALLOWED"
+	ErrCodeInternal                ErrorCode = "INTERNAL_ERROR"
+)
+
+type APIError struct {
+	Status  int
+	Code    ErrorCode
+	Message string
+	Details interface{}
+	// ログにだけ出してクライアントには返さない
+	Internal error
+}
+
+func (e *APIError) Error() string {
+	if e.Internal != nil {
+		return e.Message + ": " + e.Internal.Error()
+	}
+	return e.Message
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Internal
+}
+
+func (e *APIError) withDetails(details interface{}) *APIError {
+	e.Details = details
+	return e
+}
+
+func newAPIError(status int, code ErrorCode, message string) *APIError {
+	return &APIError{
+		Status:  status,
+		Code:    code,
+		Message: message,
+	}
+}
+
+// message はログ用。クライアントには "internal server error" だけを返す
+func internalError(message string, err error) *APIError {
+	return &APIError{
+		Status:   http.StatusInternalServerError,
+		Code:     ErrCodeInternal,
+		Message:  message,
+		Internal: err,
+	}
+}
+
+type ErrorResponse struct {
+	Code      ErrorCode   `json:"code"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+}
+
+// ハンドラが返したエラーを APIError にそろえる。エラーハンドラ・アクセスログ・メトリクスで同じ判定を使う
+func toAPIError(err error) *APIError {
+	apiErr := &APIError{}
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	he := &echo.HTTPError{}
+	if errors.As(err, &he) {
+		return fromEchoHTTPError(he)
+	}
+	return internalError("unhandled error", err)
+}
+
+// 5xx の中身はアクセスログの error に出るので、ここではクライアントに request_id だけ返す
+func httpErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	apiErr := toAPIError(err)
+	res := ErrorResponse{
+		Code:    apiErr.Code,
+		Message: apiErr.Message,
+		Details: apiErr.Details,
+	}
+	if apiErr.Status >= http.StatusInternalServerError {
+		res.Message = "internal server error"
+		res.RequestID = c.Response().Header().Get(echo.HeaderXRequestID)
+	}
+
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(apiErr.Status)
+	} else {
+		err = c.JSON(apiErr.Status, res)
+	}
+	if err != nil {
+		c.Logger().Error(err)
+	}
+}
+
+// echo 自体が返すエラー (ルーティングの 404 など) をコード付きに変換する
+func fromEchoHTTPError(he *echo.HTTPError) *APIError {
+	message := http.StatusText(he.Code)
+	if m, ok := he.Message.(string); ok {
+		message = m
+	}
+	switch he.Code {
+	case http.StatusNotFound:
+		return newAPIError(he.Code, ErrCodeNotFound, message)
+	case http.StatusMethodNotAllowed:
+		return newAPIError(he.Code, ErrCodeMethodNotAllowed, message)
+	case http.StatusUnauthorized:
+		return newAPIError(he.Code, ErrCodeNotLoggedIn, message)
+	}
+	if he.Code >= http.StatusInternalServerError {
+		return &APIError{Status: he.Code, Code: ErrCodeInternal, Message: message, Internal: he.Internal}
+	}
+	return newAPIError(he.Code, ErrCodeInvalidRequest, message)
+}
+
+// すでに APIError ならそのまま返し、そうでなければ内部エラーとして包む
+func asAPIError(message string, err error) error {
+	apiErr := &APIError{}
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	return internalError(message, err)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// サブタスクで最初に満点を取ったチーム (first blood)
+type FirstSolve struct {
+	SubtaskID int       `db:"subtask_id"`
+	TaskID    int       `db:"task_id"`
+	TeamID    int       `db:"team_id"`
+	UserID    int       `db:"user_id"`
+	SolvedAt  time.Time `db:"solved_at"`
+}
+
+// 満点を取った提出か。満点が 0 のサブタスクは解いたことにしない
+// 採点したときと作り直したときで同じ結果になるように、どちらもこれで判定する
+func solvesSubtask(score int, subtaskMaxScore int) bool {
+	return score > 0 && score >= subtaskMaxScore
+}
+
+// 失格のチームは first blood を取れない (次に解いたチームのものになる)
+func firstSolveEligible(team Team) bool {
+	return team.Status != teamStatusDisqualified && team.Status != teamStatusHidden
+}
+
+// 採点したときに呼ぶ。submission が満点で、今の記録より早ければ記録する
+// 時刻はサーバーが受け取った時刻を使う (submitted_at はクライアントが好きな値を送れる)
+func recordFirstSolve(ctx context.Context, st Store, team Team, submission Submission, subtaskMaxScore int) error {
+	if !solvesSubtask(submission.Score, subtaskMaxScore) || !firstSolveEligible(team) {
+		return nil
+	}
+	return st.FirstSolves().SaveIfEarlier(ctx, FirstSolve{
+		SubtaskID: submission.SubTaskID,
+		TaskID:    submission.TaskID,
+		TeamID:    team.ID,
+		UserID:    submission.UserID,
+		SolvedAt:  submission.ReceivedAt,
+	})
+}
+
+// 採点し直したときや、チームを消したり失格にしたり、メンバーが変わったときに提出から作り直す
+// チームは今の所属で数える
+func recomputeFirstSolves(ctx context.Context, st Store) error {
+	// 作り直している間に空の表が見えないように、まとめて入れ替える
+	return st.WithTx(ctx, func(st Store) error {
+		if err := st.FirstSolves().DeleteAll(ctx); err != nil {
+			return fmt.Errorf("failed to delete first solves: %w", err)
+		}
+		teams, err := st.Teams().List(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to select teams: %w", err)
+		}
+		userTeam := map[int]Team{}
+		for _, team := range teams {
+			for _, id := range []int{team.LeaderID, team.Member1ID, team.Member2ID} {
+				if id != nulluserid {
+					userTeam[id] = team
+				}
+			}
+		}
+
+		subs, err := st.Submissions().List(ctx, SubmissionFilter{ScoredOnly: true})
+		if err != nil {
+			return fmt.Errorf("failed to select submissions: %w", err)
+		}
+		// 受け取った順 (同じ秒なら ID の順)
+		slices.SortFunc(subs, func(a, b Submission) int {
+			if n := a.ReceivedAt.Compare(b.ReceivedAt); n != 0 {
+				return n
+			}
+			return cmp.Compare(a.ID, b.ID)
+		})
+		maxScores := map[int]int{}
+		solved := map[int]bool{}
+		for _, sub := range subs {
+			team, ok := userTeam[sub.UserID]
+			if !ok || solved[sub.SubTaskID] || !firstSolveEligible(team) {
+				continue
+			}
+			maxscore, ok := maxScores[sub.SubTaskID]
+			if !ok {
+				if maxscore, err = st.Tasks().SubtaskMaxScore(ctx, sub.SubTaskID); err != nil {
+					return fmt.Errorf("failed to select subtask max score: %w", err)
+				}
+				maxScores[sub.SubTaskID] = maxscore
+			}
+			if !solvesSubtask(sub.Score, maxscore) {
+				continue
+			}
+			err := st.FirstSolves().SaveIfEarlier(ctx, FirstSolve{
+				SubtaskID: sub.SubTaskID,
+				TaskID:    sub.TaskID,
+				TeamID:    team.ID,
+				UserID:    sub.UserID,
+				SolvedAt:  sub.ReceivedAt,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to insert first solve: %w", err)
+			}
+			solved[sub.SubTaskID] = true
+		}
+		return nil
+	})
+}
+
+// userID がチームに入ったときに呼ぶ。前のチーム (消されたものなど) で満点を取っていれば今のチームに付け替える
+// 得点のある提出がないユーザー (ほとんどの場合) では作り直さない
+func recomputeFirstSolvesForMember(ctx context.Context, st Store, userID int) error {
+	subs, err := st.Submissions().List(ctx, SubmissionFilter{UserID: userID, ScoredOnly: true})
+	if err != nil {
+		return fmt.Errorf("failed to select submissions: %w", err)
+	}
+	if len(subs) == 0 {
+		return nil
+	}
+	return recomputeFirstSolves(ctx, st)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+var testEpoch = time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+// チーム alpha (ユーザー 1, 2)、beta (3)、失格の gamma (4)
+// サブタスク 1 は満点 100、サブタスク 2 は満点 0
+func newFirstSolveStore() *fakeStore {
+	f := newFakeStore()
+	f.teams = []Team{
+		{ID: 1, Name: "alpha", LeaderID: 1, Member1ID: 2, Member2ID: nulluserid, Status: teamStatusOfficial},
+		{ID: 2, Name: "beta", LeaderID: 3, Member1ID: nulluserid, Member2ID: nulluserid, Status: teamStatusOfficial},
+		{ID: 3, Name: "gamma", LeaderID: 4, Member1ID: nulluserid, Member2ID: nulluserid, Status: teamStatusDisqualified},
+	}
+	f.answers = []Answer{
+		{ID: 1, TaskID: 1, SubtaskID: 1, Answer: "half", Score: 50},
+		{ID: 2, TaskID: 1, SubtaskID: 1, Answer: "full", Score: 100},
+		{ID: 3, TaskID: 1, SubtaskID: 2, Answer: "zero", Score: 0},
+	}
+	return f
+}
+
+// 提出はどれも submitted_at を 0 にして送られてきたことにする (first blood には関係しない)
+func addSubmission(f *fakeStore, userID int, subtaskID int, score int, at time.Duration) Submission {
+	sub := Submission{
+		ID:          len(f.submissions) + 1,
+		TaskID:      1,
+		UserID:      userID,
+		SubmittedAt: time.Unix(0, 0),
+		SubTaskID:   subtaskID,
+		Score:       score,
+		ReceivedAt:  testEpoch.Add(at),
+	}
+	f.submissions = append(f.submissions, sub)
+	return sub
+}
+
+// 採点した順に recordFirstSolve を呼ぶ
+func recordAll(t *testing.T, f *fakeStore) {
+	t.Helper()
+	ctx := context.Background()
+	for _, sub := range f.submissions {
+		team, err := f.Teams().GetByMember(ctx, sub.UserID)
+		if err != nil {
+			t.Fatalf("GetByMember(%d): %v", sub.UserID, err)
+		}
+		maxscore, _ := f.Tasks().SubtaskMaxScore(ctx, sub.SubTaskID)
+		if err := recordFirstSolve(ctx, f, team, sub, maxscore); err != nil {
+			t.Fatalf("recordFirstSolve: %v", err)
+		}
+	}
+}
+
+func TestRecordFirstSolve(t *testing.T) {
+	tests := []struct {
+		name     string
+		submit   func(f *fakeStore)
+		wantTeam int // 0 なら記録なし
+		wantAt   time.Duration
+	}{
+		{
+			name: "first full score wins",
+			submit: func(f *fakeStore) {
+				addSubmission(f, 3, 1, 100, time.Minute)
+				addSubmission(f, 1, 1, 100, 2*time.Minute)
+			},
+			wantTeam: 2,
+			wantAt:   time.Minute,
+		},
+		{
+			name:   "partial score does not count",
+			submit: func(f *fakeStore) { addSubmission(f, 1, 1, 50, time.Minute) },
+		},
+		{
+			name: "earlier solve replaces a later record",
+			submit: func(f *fakeStore) {
+				addSubmission(f, 3, 1, 100, 2*time.Minute)
+				addSubmission(f, 2, 1, 100, time.Minute)
+			},
+			wantTeam: 1,
+			wantAt:   time.Minute,
+		},
+		{
+			name: "same time keeps the first graded",
+			submit: func(f *fakeStore) {
+				addSubmission(f, 3, 1, 100, time.Minute)
+				addSubmission(f, 1, 1, 100, time.Minute)
+			},
+			wantTeam: 2,
+			wantAt:   time.Minute,
+		},
+		{
+			name: "disqualified team is skipped",
+			submit: func(f *fakeStore) {
+				addSubmission(f, 4, 1, 100, time.Minute)
+				addSubmission(f, 3, 1, 100, 2*time.Minute)
+			},
+			wantTeam: 2,
+			wantAt:   2 * time.Minute,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFirstSolveStore()
+			tt.submit(f)
+			recordAll(t, f)
+
+			fs, err := f.FirstSolves().Get(context.Background(), 1)
+			if tt.wantTeam == 0 {
+				if err == nil {
+					t.Fatalf("got first solve %+v, want none", fs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if fs.TeamID != tt.wantTeam || !fs.SolvedAt.Equal(testEpoch.Add(tt.wantAt)) {
+				t.Errorf("got team %d at %v, want team %d at %v", fs.TeamID, fs.SolvedAt, tt.wantTeam, testEpoch.Add(tt.wantAt))
+			}
+		})
+	}
+}
+
+func TestRecordFirstSolveZeroMaxScore(t *testing.T) {
+	f := newFirstSolveStore()
+	addSubmission(f, 1, 2, 0, time.Minute)
+	recordAll(t, f)
+	if _, err := f.FirstSolves().Get(context.Background(), 2); err == nil {
+		t.Fatal("subtask with max score 0 was recorded as solved")
+	}
+}
+
+// 採点しながら記録したものと、提出から作り直したものが一致する
+func TestRecomputeFirstSolvesMatchesRecord(t *testing.T) {
+	f := newFirstSolveStore()
+	addSubmission(f, 4, 1, 100, time.Minute)
+	addSubmission(f, 1, 1, 50, 2*time.Minute)
+	addSubmission(f, 3, 1, 100, 4*time.Minute)
+	addSubmission(f, 2, 1, 100, 3*time.Minute)
+	addSubmission(f, 1, 2, 0, 3*time.Minute)
+	recordAll(t, f)
+	recorded := map[int]FirstSolve{}
+	for k, v := range f.firstSolves {
+		recorded[k] = v
+	}
+
+	if err := recomputeFirstSolves(context.Background(), f); err != nil {
+		t.Fatalf("recomputeFirstSolves: %v", err)
+	}
+	if len(f.firstSolves) != len(recorded) {
+		t.Fatalf("got %d first solves, want %d", len(f.firstSolves), len(recorded))
+	}
+	for k, want := range recorded {
+		if got := f.firstSolves[k]; got != want {
+			t.Errorf("subtask %d: got %+v, want %+v", k, got, want)
+		}
+	}
+	if got := f.firstSolves[1]; got.TeamID != 1 || got.UserID != 2 {
+		t.Errorf("got %+v, want team 1 user 2", got)
+	}
+}
+
+func TestRecomputeFirstSolvesAfterMembershipChange(t *testing.T) {
+	f := newFirstSolveStore()
+	addSubmission(f, 3, 1, 100, time.Minute)
+	addSubmission(f, 1, 1, 100, 2*time.Minute)
+	recordAll(t, f)
+
+	// beta のユーザー 3 が alpha に移る
+	f.teams[0].Member2ID = 3
+	f.teams = f.teams[:1:1]
+	if err := recomputeFirstSolvesForMember(context.Background(), f, 3); err != nil {
+		t.Fatalf("recomputeFirstSolvesForMember: %v", err)
+	}
+	if got := f.firstSolves[1]; got.TeamID != 1 || got.UserID != 3 {
+		t.Errorf("got %+v, want team 1 user 3", got)
+	}
+
+	// どのチームにもいないユーザーの提出は数えない
+	f.teams[0].Member2ID = nulluserid
+	if err := recomputeFirstSolves(context.Background(), f); err != nil {
+		t.Fatalf("recomputeFirstSolves: %v", err)
+	}
+	if got := f.firstSolves[1]; got.UserID != 1 {
+		t.Errorf("got %+v, want user 1", got)
+	}
+}
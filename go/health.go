@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type HealthResponse struct {
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// 起動時に問題とサブタスクの情報を読み込んでおく。最初のベンチマークのリクエストで DB に集中しないように
+func (s *Server) warmCaches(ctx context.Context) error {
+	tasks, err := s.store.Tasks().List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to select tasks: %w", err)
+	}
+	for _, task := range tasks {
+		subtasks, err := s.getSubtasks(ctx, task.ID)
+		if err != nil {
+			return fmt.Errorf("failed to select subtasks: %w", err)
+		}
+		for _, subtask := range subtasks {
+			if _, err := s.getSubtaskMaxScore(ctx, subtask.ID); err != nil {
+				return fmt.Errorf("failed to get subtask max score: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// GET /healthz
+// プロセスが動いていれば 200 を返す (systemd の監視用)
+func (s *Server) healthzHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, HealthResponse{Status: "ok"})
+}
+
+// GET /readyz
+// キャッシュの準備ができていて DB に繋がるときだけ 200 を返す。停止処理中は 503
+func (s *Server) readyzHandler(c echo.Context) error {
+	if !s.ready.Load() {
+		return c.JSON(http.StatusServiceUnavailable, HealthResponse{Status: "unavailable", Reason: "not ready"})
+	}
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 2*time.Second)
+	defer cancel()
+	if err := s.store.Ping(ctx); err != nil {
+		return c.JSON(http.StatusServiceUnavailable, HealthResponse{Status: "unavailable", Reason: "db unreachable"})
+	}
+	return c.JSON(http.StatusOK, HealthResponse{Status: "ok"})
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ハンドラがチームを調べたら c.Set(logTeamKey, team.Name) しておくとアクセスログに出る
+const logTeamKey = "log_team"
+
+func setLogTeam(c echo.Context, teamName string) {
+	c.Set(logTeamKey, teamName)
+}
+
+// 1 リクエスト分のアクセスログ
+type accessLogEntry struct {
+	Time      time.Time
+	RequestID string
+	RemoteIP  string
+	Host      string
+	Method    string
+	URI       string
+	Proto     string
+	Route     string
+	Status    int
+	Size      int64
+	Latency   time.Duration
+	Referer   string
+	UserAgent string
+	User      string
+	Team      string
+	ErrorCode ErrorCode
+	Error     string
+}
+
+type accessLogger interface {
+	Log(entry accessLogEntry)
+}
+
+func newAccessLogger(cfg LogConfig) (accessLogger, error) {
+	var w io.Writer = os.Stdout
+	if cfg.AccessLogPath != "" {
+		f, err := os.OpenFile(cfg.AccessLogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+		w = f
+	}
+	switch cfg.Format {
+	case "ltsv":
+		return &ltsvAccessLogger{w: w}, nil
+	default:
+		return &jsonAccessLogger{logger: slog.New(slog.NewJSONHandler(w, nil))}, nil
+	}
+}
+
+type jsonAccessLogger struct {
+	logger *slog.Logger
+}
+
+func (l *jsonAccessLogger) Log(e accessLogEntry) {
+	level := slog.LevelInfo
+	if e.Status >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	attrs := []slog.Attr{
+		slog.String("request_id", e.RequestID),
+		slog.String("remote_ip", e.RemoteIP),
+		slog.String("method", e.Method),
+		slog.String("uri", e.URI),
+		slog.String("route", e.Route),
+		slog.Int("status", e.Status),
+		slog.Int64("size", e.Size),
+		slog.Float64("latency", e.Latency.Seconds()),
+		slog.String("user_agent", e.UserAgent),
+	}
+	if e.User != "" {
+		attrs = append(attrs, slog.String("user", e.User))
+	}
+	if e.Team != "" {
+		attrs = append(attrs, slog.String("team", e.Team))
+	}
+	if e.ErrorCode != "" {
+		attrs = append(attrs, slog.String("error_code", string(e.ErrorCode)))
+	}
+	if e.Error != "" {
+		attrs = append(attrs, slog.String("error", e.Error))
+	}
+	l.logger.LogAttrs(context.Background(), level, "access", attrs...)
+}
+
+// nginx.conf の log_format ltsv と同じラベルを使うので、after.sh の alp ltsv でそのまま集計できる
+type ltsvAccessLogger struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *ltsvAccessLogger) Log(e accessLogEntry) {
+	fields := [][2]string{
+		{"time", e.Time.Format("02/Jan/2006:15:04:05 -0700")},
+		{"host", e.RemoteIP},
+		{"req", e.Method + " " + e.URI + " " + e.Proto},
+		{"status", strconv.Itoa(e.Status)},
+		{"method", e.Method},
+		{"uri", e.URI},
+		{"size", strconv.FormatInt(e.Size, 10)},
+		{"referer", e.Referer},
+		{"ua", e.UserAgent},
+		{"reqtime", fmt.Sprintf("%.3f", e.Latency.Seconds())},
+		{"apptime", fmt.Sprintf("%.3f", e.Latency.Seconds())},
+		{"vhost", e.Host},
+		{"request_id", e.RequestID},
+		{"route", e.Route},
+		{"user", e.User},
+		{"team", e.Team},
+		{"error_code", string(e.ErrorCode)},
+		{"error", e.Error},
+	}
+	b := strings.Builder{}
+	for i, f := range fields {
+		if i > 0 {
+			b.WriteByte('\t')
+		}
+		b.WriteString(f[0])
+		b.WriteByte(':')
+		b.WriteString(ltsvEscape(f[1]))
+	}
+	b.WriteByte('\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	io.WriteString(l.w, b.String())
+}
+
+// 値にタブや改行が入ると行が壊れるので空白にする
+func ltsvEscape(v string) string {
+	if v == "" {
+		return "-"
+	}
+	return strings.NewReplacer("\t", " ", "\n", " ", "\r", " ").Replace(v)
+}
+
+// middleware.Logger() の代わり。誰のリクエストか (ユーザー名・チーム名) とエラーコードも出す
+func accessLogMiddleware(logger accessLogger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+			if err != nil {
+				// 先にエラーレスポンスを書いて、ステータスとサイズを確定させる
+				c.Error(err)
+			}
+
+			req := c.Request()
+			res := c.Response()
+			entry := accessLogEntry{
+				Time:      start,
+				RequestID: res.Header().Get(echo.HeaderXRequestID),
+				RemoteIP:  c.RealIP(),
+				Host:      req.Host,
+				Method:    req.Method,
+				URI:       req.RequestURI,
+				Proto:     req.Proto,
+				Route:     c.Path(),
+				Status:    res.Status,
+				Size:      res.Size,
+				Latency:   time.Since(start),
+				Referer:   req.Referer(),
+				UserAgent: req.UserAgent(),
+			}
+			entry.User, _ = loginUserName(c)
+			entry.Team, _ = c.Get(logTeamKey).(string)
+			if err != nil {
+				apiErr := toAPIError(err)
+				entry.ErrorCode = apiErr.Code
+				if apiErr.Status >= http.StatusInternalServerError {
+					entry.Error = apiErr.Error()
+				}
+			}
+			logger.Log(entry)
+			return err
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ログイン試行の結果 (login_attempts.reason)
+const (
+	loginReasonOK          = "ok"
+	loginReasonUnknownUser = "unknown_user"
+	loginReasonBadPassword = "bad_password"
+	loginReasonLocked      = "locked"
+	loginReasonDisabled    = "disabled"
+)
+
+// アカウントのページで見せる件数
+const recentLoginsLimit = 20
+
+type LoginAttempt struct {
+	ID          int       `db:"id"`
+	UserName    string    `db:"user_name"`
+	IP          string    `db:"ip"`
+	UserAgent   string    `db:"user_agent"`
+	Succeeded   bool      `db:"succeeded"`
+	Reason      string    `db:"reason"`
+	AttemptedAt time.Time `db:"attempted_at"`
+}
+
+type LoginLockout struct {
+	UserName    string    `db:"user_name"`
+	LockedUntil time.Time `db:"locked_until"`
+	// これより前の失敗は数えない
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+func (s *Server) recordLoginAttempt(c echo.Context, userName string, succeeded bool, reason string) error {
+	err := s.store.Logins().CreateAttempt(c.Request().Context(), LoginAttempt{
+		UserName:    truncateString(userName, maxNameLength),
+		IP:          truncateString(c.RealIP(), maxIPLength),
+		UserAgent:   truncateString(c.Request().UserAgent(), maxUserAgentLength),
+		Succeeded:   succeeded,
+		Reason:      reason,
+		AttemptedAt: dbNow(),
+	})
+	if err != nil {
+		return internalError("failed to record login attempt", err)
+	}
+	return nil
+}
+
+// ロック中なら 429 を返す。ロック中の試行も記録する
+func (s *Server) checkLockout(c echo.Context, userName string) error {
+	if s.cfg.Login.MaxFailures <= 0 {
+		return nil
+	}
+	lockout, err := s.store.Logins().GetLockout(c.Request().Context(), userName)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return internalError("failed to get lockout", err)
+	}
+	now := dbNow()
+	if !lockout.LockedUntil.After(now) {
+		return nil
+	}
+	if err := s.recordLoginAttempt(c, userName, false, loginReasonLocked); err != nil {
+		return err
+	}
+	seconds := int(math.Ceil(lockout.LockedUntil.Sub(now).Seconds()))
+	c.Response().Header().Set("Retry-After", strconv.Itoa(seconds))
+	return newAPIError(http.StatusTooManyRequests, ErrCodeAccountLocked, "too many failed login attempts").withDetails(map[string]int64{
+		"locked_until": lockout.LockedUntil.Unix(),
+	})
+}
+
+// パスワード違いを記録し、window の間に max_failures 回を超えたらロックする
+func (s *Server) recordLoginFailure(c echo.Context, userName string) error {
+	if err := s.recordLoginAttempt(c, userName, false, loginReasonBadPassword); err != nil {
+		return err
+	}
+	if s.cfg.Login.MaxFailures <= 0 {
+		return nil
+	}
+	ctx := c.Request().Context()
+	now := dbNow()
+	since := now.Add(-s.cfg.Login.FailureWindow)
+	lockout, err := s.store.Logins().GetLockout(ctx, userName)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return internalError("failed to get lockout", err)
+	} else if err == nil && lockout.UpdatedAt.After(since) {
+		since = lockout.UpdatedAt
+	}
+	failures, err := s.store.Logins().CountFailuresSince(ctx, userName, since)
+	if err != nil {
+		return internalError("failed to count login failures", err)
+	}
+	if failures < s.cfg.Login.MaxFailures {
+		return nil
+	}
+	err = s.store.Logins().SaveLockout(ctx, LoginLockout{
+		UserName:    userName,
+		LockedUntil: now.Add(s.cfg.Login.LockoutDuration),
+		UpdatedAt:   now,
+	})
+	if err != nil {
+		return internalError("failed to save lockout", err)
+	}
+	return nil
+}
+
+type LoginAttemptResponse struct {
+	IP          string `json:"ip"`
+	UserAgent   string `json:"user_agent"`
+	Succeeded   bool   `json:"succeeded"`
+	Reason      string `json:"reason"`
+	AttemptedAt int64  `json:"attempted_at"`
+}
+
+// GET /api/account/logins
+// 自分のアカウントへの最近のログイン試行 (失敗したものも含む)
+func (s *Server) getAccountLoginsHandler(c echo.Context) error {
+	if err := verifyUserSession(c); err != nil {
+		return err
+	}
+	username, _ := loginUserName(c)
+
+	attempts, err := s.store.Logins().ListAttempts(c.Request().Context(), username, recentLoginsLimit)
+	if err != nil {
+		return internalError("failed to get login attempts", err)
+	}
+	res := make([]LoginAttemptResponse, 0, len(attempts))
+	for _, a := range attempts {
+		res = append(res, LoginAttemptResponse{
+			IP:          a.IP,
+			UserAgent:   a.UserAgent,
+			Succeeded:   a.Succeeded,
+			Reason:      a.Reason,
+			AttemptedAt: a.AttemptedAt.Unix(),
+		})
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+type LockoutResponse struct {
+	UserName    string `json:"user_name"`
+	LockedUntil int64  `json:"locked_until"`
+	LockedAt    int64  `json:"locked_at"`
+}
+
+// GET /api/admin/lockouts
+// 今ロックされているアカウント
+func (s *Server) getLockoutsHandler(c echo.Context) error {
+	if err := verifyAdminSession(c); err != nil {
+		return err
+	}
+	lockouts, err := s.store.Logins().ListLockouts(c.Request().Context(), dbNow())
+	if err != nil {
+		return internalError("failed to get lockouts", err)
+	}
+	res := make([]LockoutResponse, 0, len(lockouts))
+	for _, l := range lockouts {
+		res = append(res, LockoutResponse{
+			UserName:    l.UserName,
+			LockedUntil: l.LockedUntil.Unix(),
+			LockedAt:    l.UpdatedAt.Unix(),
+		})
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+type ClearLockoutRequest struct {
+	UserName string `json:"user_name"`
+}
+
+// POST /api/admin/lockouts/clear
+// ロックを解除し、それまでの失敗の回数もなかったことにする
+func (s *Server) clearLockoutHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+	defer c.Request().Body.Close()
+
+	if err := verifyAdminSession(c); err != nil {
+		return err
+	}
+	req := ClearLockoutRequest{}
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return newAPIError(http.StatusBadRequest, ErrCodeInvalidRequest, "failed to decode the request body as json")
+	}
+	if err := s.clearLockout(ctx, req.UserName); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func (s *Server) clearLockout(ctx context.Context, userName string) error {
+	if _, err := s.store.Users().GetByName(ctx, userName); errors.Is(err, ErrNotFound) {
+		return newAPIError(http.StatusNotFound, ErrCodeUserNotFound, "user not found")
+	} else if err != nil {
+		return internalError("failed to get user", err)
+	}
+	now := dbNow()
+	if err := s.store.Logins().SaveLockout(ctx, LoginLockout{UserName: userName, LockedUntil: now, UpdatedAt: now}); err != nil {
+		return internalError("failed to clear lockout", err)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo-contrib/session"
+	"github.com/labstack/echo/v4"
+)
+
+func newLoginTestServer(t *testing.T) (*Server, *fakeStore) {
+	t.Helper()
+	f := newFakeStore()
+	f.users = []User{{ID: 1, Name: "alice", DisplayName: "Alice", Passhash: calcsha256("correct")}}
+	if f.users[0].Passhash == "" {
+		t.Skip("/bin/sha256sum is not available")
+	}
+	s := newTestServer(f)
+	s.cfg.Login.MaxFailures = 3
+	s.cfg.Login.FailureWindow = 15 * time.Minute
+	s.cfg.Login.LockoutDuration = 15 * time.Minute
+	return s, f
+}
+
+// ログインして、成功なら 200、失敗なら APIError の status と code を返す
+func tryLogin(t *testing.T, s *Server, password string) (int, ErrorCode) {
+	t.Helper()
+	e := echo.New()
+	body := `{"name":"alice","password":"` + password + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/login", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	err := session.Middleware(s.sessions)(s.loginHandler)(c)
+	if err == nil {
+		return rec.Code, ""
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("login: %v", err)
+	}
+	return apiErr.Status, apiErr.Code
+}
+
+func TestLoginLockout(t *testing.T) {
+	s, f := newLoginTestServer(t)
+
+	for i := 0; i < 3; i++ {
+		if status, code := tryLogin(t, s, "wrong"); status != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: got %d %s, want 401", i+1, status, code)
+		}
+	}
+	// ロック中は正しいパスワードでも入れない
+	if status, code := tryLogin(t, s, "correct"); status != http.StatusTooManyRequests || code != ErrCodeAccountLocked {
+		t.Fatalf("got %d %s, want 429 %s", status, code, ErrCodeAccountLocked)
+	}
+	if last := f.attempts[len(f.attempts)-1]; last.Reason != loginReasonLocked || last.Succeeded {
+		t.Errorf("got last attempt %+v, want a failed %q attempt", last, loginReasonLocked)
+	}
+
+	// ロックが切れたら入れる。ロック前の失敗はもう数えない
+	lockout := f.lockouts["alice"]
+	lockout.LockedUntil = dbNow().Add(-time.Second)
+	f.lockouts["alice"] = lockout
+	if status, code := tryLogin(t, s, "wrong"); status != http.StatusUnauthorized {
+		t.Fatalf("after lockout: got %d %s, want 401", status, code)
+	}
+	if status, code := tryLogin(t, s, "correct"); status != http.StatusOK {
+		t.Fatalf("after lockout: got %d %s, want 200", status, code)
+	}
+}
+
+func TestLoginSuccessResetsFailures(t *testing.T) {
+	s, f := newLoginTestServer(t)
+
+	tryLogin(t, s, "wrong")
+	tryLogin(t, s, "wrong")
+	// 時刻は秒単位なので、同じ秒の成功より前の失敗は区別できない。1 分前の失敗にする
+	for i := range f.attempts {
+		f.attempts[i].AttemptedAt = f.attempts[i].AttemptedAt.Add(-time.Minute)
+	}
+	// 成功より前の失敗は数えないので、失敗が合わせて max_failures 回になってもロックしない
+	for _, password := range []string{"correct", "wrong", "wrong"} {
+		tryLogin(t, s, password)
+	}
+	if status, code := tryLogin(t, s, "correct"); status != http.StatusOK {
+		t.Fatalf("got %d %s, want 200", status, code)
+	}
+}
+
+func TestLoginLockoutDisabled(t *testing.T) {
+	s, f := newLoginTestServer(t)
+	s.cfg.Login.MaxFailures = 0
+
+	for i := 0; i < 5; i++ {
+		tryLogin(t, s, "wrong")
+	}
+	if status, code := tryLogin(t, s, "correct"); status != http.StatusOK {
+		t.Fatalf("got %d %s, want 200", status, code)
+	}
+	if len(f.lockouts) != 0 {
+		t.Errorf("got lockouts %v, want none", f.lockouts)
+	}
+}
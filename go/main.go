@@ -3,41 +3,56 @@ package main
 // sqlx については https://jmoiron.github.io/sqlx/ を参照
 
 import (
-	// "fmt"
-	"database/sql"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"net"
 	"net/http"
 	"os"
-	"os/exec"
-	"strconv"
-	"sync"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
 
 	"github.com/go-sql-driver/mysql"
-	"github.com/gorilla/sessions"
 	"github.com/jmoiron/sqlx"
 	"github.com/labstack/echo-contrib/session"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	echolog "github.com/labstack/gommon/log"
-
-	_ "net/http/pprof"
-)
-
-const (
-	listenPort           = 8080
-	frontendContentsPath = "../public"
-)
-
-var (
-	dbConn *sqlx.DB
-	secret = []byte("risucon_session_cookiestore_defaultsecret")
+	"github.com/prometheus/client_golang/prometheus/collectors"
 )
 
 func init() {
 	log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
-	if secretKey, ok := os.LookupEnv("RISUCON_SESSION_SECRETKEY"); ok {
-		secret = []byte(secretKey)
+}
+
+// ハンドラはこれのメソッドとして実装し、DB には store 経由でアクセスする
+type Server struct {
+	cfg      Config
+	store    Store
+	cache    *Caches
+	metrics  *Metrics
+	limits   *RateLimits
+	sessions *serverSessionStore
+	// 起動処理が終わってから停止処理が始まるまで true (/readyz 用)
+	ready atomic.Bool
+}
+
+func newServer(cfg Config, store Store) *Server {
+	cache := newCaches(cfg.Cache.TTL, cfg.Cache.MaxEntries)
+	var sessionBackend SessionStore = store.Sessions()
+	if cfg.Session.Store == "memory" {
+		sessionBackend = newMemorySessionStore()
+	}
+	return &Server{
+		cfg:      cfg,
+		store:    store,
+		cache:    cache,
+		metrics:  newMetrics(cache),
+		limits:   newRateLimits(cfg.RateLimit),
+		sessions: newServerSessionStore(sessionBackend, cfg.Session),
 	}
 }
 
@@ -53,48 +68,47 @@ func getEnv(key string, defaultValue string) string {
 	return defaultValue
 }
 
-// DBに接続する
-func connectDB() (*sqlx.DB, error) {
-	config := mysql.NewConfig()
-	config.Net = "tcp"
-	config.Addr = getEnv("RISUCON_DB_HOST", "127.0.0.1") + ":" + getEnv("RISUCON_DB_PORT", "3306")
-	config.User = getEnv("RISUCON_DB_USER", "risucon")
-	config.Passwd = getEnv("RISUCON_DB_PASSWORD", "risucon")
-	config.DBName = getEnv("RISUCON_DB_NAME", "risucontest")
-	config.ParseTime = true
-	config.Params = map[string]string{"interpolateParams": "true"}
-	dsn := config.FormatDSN()
-	return sqlx.Open("mysql", dsn)
+// DBに接続する。db.driver で MySQL か SQLite かを選ぶ
+func connectDB(cfg DBConfig) (*sqlx.DB, error) {
+	var db *sqlx.DB
+	var err error
+	switch cfg.Driver {
+	case "mysql":
+		config := mysql.NewConfig()
+		config.Net = "tcp"
+		config.Addr = net.JoinHostPort(cfg.Host, cfg.Port)
+		config.User = cfg.User
+		config.Passwd = cfg.Password
+		config.DBName = cfg.Name
+		config.ParseTime = true
+		config.Timeout = cfg.ConnectTimeout
+		config.ReadTimeout = cfg.ReadTimeout
+		config.WriteTimeout = cfg.WriteTimeout
+		config.Params = map[string]string{"interpolateParams": "true"}
+		db, err = sqlx.Open("mysql", config.FormatDSN())
+	case "sqlite3":
+		db, err = connectSQLite(cfg.SQLitePath)
+	default:
+		return nil, fmt.Errorf("unknown db driver: %s", cfg.Driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	return db, nil
 }
 
-func initializeHandler(c echo.Context) error {
-	if out, err := exec.Command("../sql/init.sh").CombinedOutput(); err != nil {
-		c.Logger().Warnf("init.sh failed with err=%s", string(out))
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to initialize: "+err.Error())
-	}
-	// score
-	subs := []Submission{}
-	if err := dbConn.Select(&subs, "SELECT * FROM submissions"); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to select submissions: "+err.Error())
-	}
-	for _, sub := range subs {
-		ans := Answer{}
-		if err := dbConn.Get(&ans, "SELECT * FROM answers WHERE task_id = ? AND answer = ?", sub.TaskID, sub.Answer); err == sql.ErrNoRows {
-			ans.Score = 0
-			ans.SubtaskID = -1
-		} else if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "failed to select answers: "+err.Error())
-		}
-		if _, err := dbConn.Exec("UPDATE submissions SET score = ? , subtask_id = ? WHERE id = ?", ans.Score, ans.SubtaskID, sub.ID); err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "failed to update submissions: "+err.Error())
-		}
+// POST /api/initialize
+// ベンチマーカー向けに残している。中身は POST /api/admin/reset/seed と同じ
+func (s *Server) initializeHandler(c echo.Context) error {
+	if err := s.verifyResetAllowed(c); err != nil {
+		return err
+	}
+	if err := s.resetToSeed(c.Request().Context()); err != nil {
+		return internalError("failed to initialize", err)
 	}
-	// キャッシュを消す
-	subtaskcache = sync.Map{}
-	standingssubcache = sync.Map{}
-	standingssubexistscache = sync.Map{}
-	usercache = sync.Map{}
-	subtaskmaxscorecache = sync.Map{}
 
 	c.Request().Header.Add("Content-Type", "application/json;charset=utf-8")
 	return c.JSON(http.StatusOK, InitializeResponse{
@@ -103,71 +117,219 @@ func initializeHandler(c echo.Context) error {
 }
 
 func main() {
-	/*http.DefaultServeMux.Handle("/debug/fgprof/profile", fgprof.Handler())
-	go func() {
-		log.Println(http.ListenAndServe(":6060", nil))
-	}()*/
-
 	e := echo.New()
 	e.Debug = false
 	e.Logger.SetLevel(echolog.ERROR)
-	e.Use(middleware.Logger())
-	cookiestore := sessions.NewCookieStore(secret)
-	e.Use(session.Middleware(cookiestore))
+
+	// 設定
+	cfg, args, printConfig, err := loadConfig(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	} else if err != nil {
+		e.Logger.Errorf("failed to load config: %v", err)
+		os.Exit(1)
+	}
+	if printConfig {
+		fmt.Print(cfg)
+		return
+	}
+
+	e.HTTPErrorHandler = httpErrorHandler
+	// nginx の後ろにいるので X-Forwarded-For から接続元を取る (信頼するのはローカル・プライベートのアドレスからのものだけ)
+	e.IPExtractor = echo.ExtractIPFromXFFHeader()
+	// X-Request-ID はレスポンスヘッダにも付く
+	e.Use(middleware.RequestID())
+	accessLogger, err := newAccessLogger(cfg.Log)
+	if err != nil {
+		e.Logger.Errorf("failed to open access log: %v", err)
+		os.Exit(1)
+	}
+	e.Use(accessLogMiddleware(accessLogger))
+
+	// DB接続
+	db, err := connectDB(cfg.DB)
+	if err != nil {
+		e.Logger.Errorf("failed to connect db: %v", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		e.Logger.Errorf("failed to ping db: %v", err)
+		os.Exit(1)
+	}
+
+	// ./risucontest migrate [up|down [N]|status]
+	if len(args) > 0 && args[0] == "migrate" {
+		if err := migrateCommand(context.Background(), db, args[1:]); err != nil {
+			e.Logger.Errorf("failed to migrate: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if cfg.DB.AutoMigrate {
+		if err := migrateUp(context.Background(), db); err != nil {
+			e.Logger.Errorf("failed to migrate: %v", err)
+			os.Exit(1)
+		}
+	}
+	if db.DriverName() == "sqlite3" {
+		if err := seedSQLiteIfEmpty(context.Background(), db); err != nil {
+			e.Logger.Errorf("failed to seed sqlite db: %v", err)
+			os.Exit(1)
+		}
+	}
+	s := newServer(cfg, newSQLStore(db))
+
+	// ./risucontest reset-seed などの管理コマンド
+	if len(args) > 0 {
+		if err := s.runCommand(context.Background(), args); err != nil {
+			e.Logger.Errorf("%s failed: %v", args[0], err)
+			os.Exit(1)
+		}
+		return
+	}
+	if err := s.writePIDFile(); err != nil {
+		e.Logger.Errorf("failed to write pid file: %v", err)
+		os.Exit(1)
+	}
+	go s.reloadCachesOnSignal()
+	go s.pruneExpiredSessions()
+
+	// セッションは DB (かメモリ) に置き、クッキーにはトークンだけを入れる
+	e.Use(session.Middleware(s.sessions))
+	// Authorization: Bearer のリクエストはセッションの代わりに API トークンで認証する
+	e.Use(s.apiTokenAuth())
+	e.Use(s.rejectDisabledSessions())
+	if cfg.Session.CSRF {
+		e.Use(s.csrfProtection())
+	}
+
+	// メトリクス
+	s.metrics.registerDB(collectors.NewDBStatsCollector(db.DB, "risucontest"))
+	e.Use(s.metrics.middleware())
+	if metricsAddr := cfg.Server.MetricsAddr; metricsAddr != "" {
+		// 別ポートで出す。外から見えないアドレス (127.0.0.1:9100 など) にすること
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", s.metrics.handler())
+		go func() {
+			if err := http.ListenAndServe(metricsAddr, mux); err != nil {
+				e.Logger.Errorf("failed to start metrics server: %v", err)
+			}
+		}()
+	} else {
+		e.GET("/metrics", s.metricsHandler)
+	}
+
+	// プロファイリング
+	s.setupProfiling(e)
+
+	// 死活監視
+	e.GET("/healthz", s.healthzHandler)
+	e.GET("/readyz", s.readyzHandler)
 
 	// 初期化
-	e.POST("/api/initialize", initializeHandler)
+	e.POST("/api/initialize", s.initializeHandler)
 
 	// user
-	e.POST("/api/register", registerHandler)
-	e.POST("/api/login", loginHandler)
-	e.POST("/api/logout", logoutHandler)
-	e.GET("/api/user/:username", getUserHandler)
+	e.GET("/api/registration", s.getRegistrationHandler)
+	e.POST("/api/register", s.registerHandler, rateLimitByIP(s.limits.Login))
+	e.POST("/api/login", s.loginHandler, rateLimitByIP(s.limits.Login))
+	e.POST("/api/logout", s.logoutHandler)
+	e.POST("/api/password-reset", s.passwordResetHandler, rateLimitByIP(s.limits.Login))
+	e.GET("/api/csrf-token", s.getCSRFTokenHandler)
+	e.GET("/api/user/:username", s.getUserHandler)
+
+	// account
+	e.POST("/api/account/profile", s.updateProfileHandler)
+	e.POST("/api/account/password", s.changePasswordHandler)
+	e.POST("/api/account/delete", s.deleteAccountHandler)
+	e.GET("/api/account/logins", s.getAccountLoginsHandler)
+	e.GET("/api/account/sessions", s.getAccountSessionsHandler)
+	e.POST("/api/account/sessions/revoke", s.revokeAccountSessionHandler)
+	e.GET("/api/account/tokens", s.getAPITokensHandler)
+	e.POST("/api/account/tokens", s.createAPITokenHandler)
+	e.POST("/api/account/tokens/revoke", s.revokeAPITokenHandler)
 
 	// team
-	e.POST("/api/team/create", createTeamHandler)
-	e.POST("/api/team/join", joinTeamHandler)
-	e.GET("/api/team/:teamname", getTeamHandler)
+	e.POST("/api/team/create", s.createTeamHandler)
+	e.POST("/api/team/join", s.joinTeamHandler)
+	e.GET("/api/team/:teamname", s.getTeamHandler)
 
 	// contest
-	e.GET("/api/tasks", getTasksHandler)
-	e.GET("/api/standings", getStandingsHandler)
-	e.GET("/api/tasks/:taskname", getTaskHandler)
-	e.POST("/api/submit", submitHandler)
-	e.GET("/api/submissions", getSubmissionsHandler)
+	e.GET("/api/tasks", s.getTasksHandler)
+	e.GET("/api/standings", s.getStandingsHandler)
+	e.GET("/api/standings/progress", s.getScoreProgressHandler)
+	e.GET("/api/tasks/:taskname", s.getTaskHandler)
+	e.GET("/api/tasks/:taskname/history", s.getTaskHistoryHandler)
+	e.POST("/api/submit", s.submitHandler)
+	e.GET("/api/submissions", s.getSubmissionsHandler)
 
 	// for admin
-	e.POST("/api/admin/createtask", createTaskHandler)
+	e.POST("/api/admin/createtask", s.createTaskHandler)
+	e.POST("/api/admin/reset/seed", s.resetSeedHandler)
+	e.POST("/api/admin/reset/submissions", s.resetSubmissionsHandler)
+	e.POST("/api/admin/reload-caches", s.reloadCachesHandler)
+	e.GET("/api/admin/cache-stats", s.getCacheStatsHandler)
+	e.GET("/api/admin/lockouts", s.getLockoutsHandler)
+	e.POST("/api/admin/lockouts/clear", s.clearLockoutHandler)
+	e.POST("/api/admin/sessions/revoke", s.revokeUserSessionsHandler)
+	e.POST("/api/admin/sessions/revoke-all", s.revokeAllSessionsHandler)
+	e.GET("/api/admin/users", s.getUsersHandler)
+	e.POST("/api/admin/users/disable", s.disableUserHandler)
+	e.POST("/api/admin/users/enable", s.enableUserHandler)
+	e.POST("/api/admin/users/reset-password", s.resetUserPasswordHandler)
+	e.POST("/api/admin/users/provision", s.provisionUsersHandler)
+	e.GET("/api/admin/registration", s.getAdminRegistrationHandler)
+	e.POST("/api/admin/registration", s.updateRegistrationHandler)
+	e.GET("/api/admin/registration/allowlist", s.getRegistrationAllowlistHandler)
+	e.POST("/api/admin/registration/allowlist", s.addRegistrationAllowlistHandler)
+	e.POST("/api/admin/registration/allowlist/remove", s.removeRegistrationAllowlistHandler)
+	e.POST("/api/admin/teams/update", s.updateTeamHandler)
+	e.POST("/api/admin/teams/delete", s.deleteTeamHandler)
+	e.POST("/api/admin/teams/status", s.setTeamStatusHandler)
 
 	// 静的ファイル
-	e.Static("/assets", frontendContentsPath+"/assets")
+	e.Static("/assets", cfg.Server.StaticPath+"/assets")
 
 	// 以上に当てはまらなければ index.html を返す
-	e.GET("/*", getIndexHandler)
+	e.GET("/*", s.getIndexHandler)
 
-	// DB接続
-	db, err := connectDB()
-	if err != nil {
-		e.Logger.Errorf("failed to connect db: %v", err)
+	if err := s.warmCaches(context.Background()); err != nil {
+		e.Logger.Errorf("failed to warm caches: %v", err)
 		os.Exit(1)
 	}
-	db.SetMaxOpenConns(1000)
-	defer db.Close()
-	if err := db.Ping(); err != nil {
-		e.Logger.Errorf("failed to ping db: %v", err)
-		os.Exit(1)
-	}
-	dbConn = db
+	s.ready.Store(true)
 
 	// サーバー起動
-	listenAddr := net.JoinHostPort("", strconv.Itoa(listenPort))
-	e.Logger.Infof("listening on %s", listenAddr)
-	if err := e.Start(listenAddr); err != nil {
-		e.Logger.Errorf("failed to start server: %v", err)
-		os.Exit(1)
+	serverErr := make(chan error, 1)
+	go func() {
+		e.Logger.Infof("listening on %s", cfg.Server.ListenAddr)
+		serverErr <- e.Start(cfg.Server.ListenAddr)
+	}()
+
+	// SIGTERM を受けたら新しい接続を断り、処理中のリクエスト (提出のトランザクションなど) が終わるのを待ってから止める
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			e.Logger.Errorf("failed to start server: %v", err)
+			os.Exit(1)
+		}
+	case <-ctx.Done():
+		s.ready.Store(false)
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+		defer cancel()
+		if err := e.Shutdown(shutdownCtx); err != nil {
+			e.Logger.Errorf("failed to shut down gracefully: %v", err)
+		}
+	}
+	if err := os.Remove(cfg.Server.PIDFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		e.Logger.Errorf("failed to remove pid file: %v", err)
 	}
+	// db は defer で閉じる
 }
 
-func getIndexHandler(c echo.Context) error {
-	return c.File(frontendContentsPath + "/index.html")
+func (s *Server) getIndexHandler(c echo.Context) error {
+	return c.File(s.cfg.Server.StaticPath + "/index.html")
 }
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+type Metrics struct {
+	registry        *prometheus.Registry
+	requestDuration *prometheus.HistogramVec
+	submissions     *prometheus.CounterVec
+}
+
+func newMetrics(cache *Caches) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "risucontest_http_request_duration_seconds",
+			Help:    "Latency of HTTP requests by route.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		submissions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "risucontest_submissions_total",
+			Help: "Number of accepted submissions by verdict.",
+		}, []string{"verdict"}),
+	}
+	m.registry.MustRegister(
+		m.requestDuration,
+		m.submissions,
+		newCacheCollector(cache),
+		// go_goroutines などはここに含まれる
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// DB の接続プールの状態 (dbConn.Stats() 相当) を出す
+func (m *Metrics) registerDB(db prometheus.Collector) {
+	m.registry.MustRegister(db)
+}
+
+func (m *Metrics) observeSubmission(scored bool) {
+	verdict := "unscored"
+	if scored {
+		verdict = "scored"
+	}
+	m.submissions.WithLabelValues(verdict).Inc()
+}
+
+func (m *Metrics) middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+			status := c.Response().Status
+			if err != nil {
+				// エラーハンドラはこのあとに呼ばれるので、エラーからステータスを決める
+				status = toAPIError(err).Status
+			}
+			// c.Path() はルートのパターン (/api/tasks/:taskname など) なのでラベルの数が増えすぎない
+			m.requestDuration.WithLabelValues(c.Request().Method, c.Path(), strconv.Itoa(status)).Observe(time.Since(start).Seconds())
+			return err
+		}
+	}
+}
+
+func (m *Metrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+type cacheCollector struct {
+	cache   *Caches
+	hits    *prometheus.Desc
+	misses  *prometheus.Desc
+	entries *prometheus.Desc
+}
+
+func newCacheCollector(cache *Caches) *cacheCollector {
+	return &cacheCollector{
+		cache:   cache,
+		hits:    prometheus.NewDesc("risucontest_cache_hits_total", "Number of cache hits by region.", []string{"region"}, nil),
+		misses:  prometheus.NewDesc("risucontest_cache_misses_total", "Number of cache misses by region.", []string{"region"}, nil),
+		entries: prometheus.NewDesc("risucontest_cache_entries", "Number of entries by region.", []string{"region"}, nil),
+	}
+}
+
+func (cc *cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cc.hits
+	ch <- cc.misses
+	ch <- cc.entries
+}
+
+func (cc *cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, st := range cc.cache.Stats() {
+		ch <- prometheus.MustNewConstMetric(cc.hits, prometheus.CounterValue, float64(st.Hits), st.Name)
+		ch <- prometheus.MustNewConstMetric(cc.misses, prometheus.CounterValue, float64(st.Misses), st.Name)
+		ch <- prometheus.MustNewConstMetric(cc.entries, prometheus.GaugeValue, float64(st.Entries), st.Name)
+	}
+}
+
+// GET /metrics
+// RISUCON_METRICS_ADDR が設定されていればそちらで認証なしで出し、メインのポートでは管理者だけに見せる
+func (s *Server) metricsHandler(c echo.Context) error {
+	if err := verifyAdminSession(c); err != nil {
+		return err
+	}
+	s.metrics.handler().ServeHTTP(c.Response(), c.Request())
+	return nil
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+)
+
+// マイグレーションは ../sql/migrations/<driver>/NNNN_name.{up,down}.sql に置く
+const migrationsDir = "../sql/migrations"
+
+type migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type appliedMigration struct {
+	Version   int       `db:"version"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+func migrationsDirFor(driver string) string {
+	if driver == "sqlite3" {
+		return migrationsDir + "/sqlite"
+	}
+	return migrationsDir + "/" + driver
+}
+
+func loadMigrations(dir string) ([]migration, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*migration{}
+	for _, file := range files {
+		base := filepath.Base(file)
+		// 0001_initial_schema.up.sql -> "0001", "initial_schema", "up"
+		prefix, rest, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("invalid migration file name: %s", base)
+		}
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version: %s", base)
+		}
+		name, direction, ok := strings.Cut(strings.TrimSuffix(rest, ".sql"), ".")
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("migration file must end with .up.sql or .down.sql: %s", base)
+		}
+		body, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, name)
+		}
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d has no up file", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// MySQL のドライバは 1 回の Exec で複数の文を受け付けないので、行末の ; で区切る
+func splitStatements(body string) []string {
+	stmts := []string{}
+	current := strings.Builder{}
+	for _, line := range strings.Split(body, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			stmts = append(stmts, current.String())
+			current.Reset()
+		}
+	}
+	if strings.TrimSpace(current.String()) != "" {
+		stmts = append(stmts, current.String())
+	}
+	return stmts
+}
+
+func ensureMigrationsTable(ctx context.Context, db *sqlx.DB) error {
+	_, err := db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS `schema_migrations` (`version` INT NOT NULL PRIMARY KEY, `applied_at` DATETIME NOT NULL)")
+	return err
+}
+
+func appliedMigrations(ctx context.Context, db *sqlx.DB) ([]appliedMigration, error) {
+	if err := ensureMigrationsTable(ctx, db); err != nil {
+		return nil, err
+	}
+	applied := []appliedMigration{}
+	err := db.SelectContext(ctx, &applied, "SELECT version, applied_at FROM schema_migrations ORDER BY version")
+	return applied, err
+}
+
+// MySQL には ADD COLUMN IF NOT EXISTS がないので、前回の途中まで適用されていたときのエラーを見分ける
+func alreadyApplied(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return false
+	}
+	switch mysqlErr.Number {
+	case 1060, // ER_DUP_FIELDNAME: Duplicate column name
+		1061: // ER_DUP_KEYNAME: Duplicate key name
+		return true
+	}
+	return false
+}
+
+func runMigration(ctx context.Context, db *sqlx.DB, body string, record func(tx *sqlx.Tx) error) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	// MySQL では DDL は暗黙にコミットされるので、途中で失敗すると前の文だけ適用されたまま残る
+	// そのまま再実行できるように、CREATE には IF NOT EXISTS を付け、ADD COLUMN の重複は適用済みとして扱う
+	for _, stmt := range splitStatements(body) {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil && !alreadyApplied(err) {
+			return err
+		}
+	}
+	if err := record(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// まだ適用されていないマイグレーションをすべて適用する
+func migrateUp(ctx context.Context, db *sqlx.DB) error {
+	migrations, err := loadMigrations(migrationsDirFor(db.DriverName()))
+	if err != nil {
+		return err
+	}
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return err
+	}
+	done := map[int]bool{}
+	for _, a := range applied {
+		done[a.Version] = true
+	}
+
+	for _, m := range migrations {
+		if done[m.Version] {
+			continue
+		}
+		err := runMigration(ctx, db, m.Up, func(tx *sqlx.Tx) error {
+			_, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)", m.Version, time.Now())
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("migration %04d_%s failed: %w", m.Version, m.Name, err)
+		}
+	}
+	return nil
+}
+
+// 新しい方から steps 個のマイグレーションを戻す
+func migrateDown(ctx context.Context, db *sqlx.DB, steps int) error {
+	migrations, err := loadMigrations(migrationsDirFor(db.DriverName()))
+	if err != nil {
+		return err
+	}
+	byVersion := map[int]migration{}
+	for _, m := range migrations {
+		byVersion[m.Version] = m
+	}
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	for i := len(applied) - 1; i >= 0 && steps > 0; i, steps = i-1, steps-1 {
+		version := applied[i].Version
+		m, ok := byVersion[version]
+		if !ok || m.Down == "" {
+			return fmt.Errorf("migration %04d has no down file", version)
+		}
+		err := runMigration(ctx, db, m.Down, func(tx *sqlx.Tx) error {
+			_, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = ?", version)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("rollback of %04d_%s failed: %w", m.Version, m.Name, err)
+		}
+	}
+	return nil
+}
+
+// ./risucontest migrate [up|down [N]|status]
+func migrateCommand(ctx context.Context, db *sqlx.DB, args []string) error {
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+	switch command {
+	case "up":
+		return migrateUp(ctx, db)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid number of steps: %s", args[1])
+			}
+			steps = n
+		}
+		return migrateDown(ctx, db, steps)
+	case "status":
+		migrations, err := loadMigrations(migrationsDirFor(db.DriverName()))
+		if err != nil {
+			return err
+		}
+		applied, err := appliedMigrations(ctx, db)
+		if err != nil {
+			return err
+		}
+		appliedAt := map[int]time.Time{}
+		for _, a := range applied {
+			appliedAt[a.Version] = a.AppliedAt
+		}
+		for _, m := range migrations {
+			status := "pending"
+			if t, ok := appliedAt[m.Version]; ok {
+				status = "applied at " + t.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%s\t%s\n", m.Version, m.Name, status)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command: %s", command)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/felixge/fgprof"
+	"github.com/labstack/echo/v4"
+)
+
+// pprof と fgprof (wall-clock) のハンドラ。before.sh は localhost:6060/debug/fgprof/profile を見に行く
+func newProfileMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/fgprof/profile", fgprof.Handler())
+	return mux
+}
+
+// server.profile_addr (RISUCON_PROFILE_ADDR=:6060 など) があれば別ポートで、
+// server.profile_on_main_port (RISUCON_PROFILE_ON_MAIN_PORT=true) ならメインのポートで管理者だけに出す
+func (s *Server) setupProfiling(e *echo.Echo) {
+	if addr := s.cfg.Server.ProfileAddr; addr != "" {
+		go func() {
+			if err := http.ListenAndServe(addr, newProfileMux()); err != nil {
+				e.Logger.Errorf("failed to start profiling server: %v", err)
+			}
+		}()
+	}
+	if s.cfg.Server.ProfileOnMainPort {
+		handler := echo.WrapHandler(newProfileMux())
+		adminOnly := func(c echo.Context) error {
+			if err := verifyAdminSession(c); err != nil {
+				return err
+			}
+			return handler(c)
+		}
+		e.GET("/debug/pprof/*", adminOnly)
+		e.POST("/debug/pprof/symbol", adminOnly)
+		e.GET("/debug/fgprof/profile", adminOnly)
+	}
+}
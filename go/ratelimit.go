@@ -0,0 +1,125 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// キーごとのトークンバケット。interval ごとに 1 つ補充され、最大 burst 個まで貯まる
+// interval が 0 なら制限しない
+type rateLimiter struct {
+	interval time.Duration
+	burst    int
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// バケットがこの数を超えたら、満タンに戻っているものを捨てる
+const rateLimiterPruneThreshold = 10000
+
+func newRateLimiter(interval time.Duration, burst int) *rateLimiter {
+	return &rateLimiter{
+		interval: interval,
+		burst:    burst,
+		buckets:  map[string]*tokenBucket{},
+	}
+}
+
+// トークンを 1 つ使う。足りなければ次のトークンが貯まるまでの時間を返す
+func (l *rateLimiter) allow(key string) (bool, time.Duration) {
+	if l.interval <= 0 {
+		return true, 0
+	}
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= rateLimiterPruneThreshold {
+			l.pruneLocked(now)
+		}
+		b = &tokenBucket{tokens: float64(l.burst), updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(l.burst), b.tokens+float64(now.Sub(b.updated))/float64(l.interval))
+	b.updated = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) * float64(l.interval))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// allow で使ったトークンを 1 つ返す。そのあとの処理が失敗して、リクエストを数えたくないときに呼ぶ
+func (l *rateLimiter) refund(key string) {
+	if l.interval <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if b, ok := l.buckets[key]; ok {
+		b.tokens = math.Min(float64(l.burst), b.tokens+1)
+	}
+}
+
+func (l *rateLimiter) pruneLocked(now time.Time) {
+	full := time.Duration(l.burst) * l.interval
+	for k, b := range l.buckets {
+		if now.Sub(b.updated) >= full {
+			delete(l.buckets, k)
+		}
+	}
+}
+
+type RateLimits struct {
+	// IP ごと。パスワードの総当たり対策
+	Login *rateLimiter
+	// チームごと
+	Submit *rateLimiter
+	// チーム・問題ごとの提出の最小間隔
+	SubmitInterval *rateLimiter
+}
+
+func newRateLimits(cfg RateLimitConfig) *RateLimits {
+	return &RateLimits{
+		Login:          newRateLimiter(cfg.LoginInterval, cfg.LoginBurst),
+		Submit:         newRateLimiter(cfg.SubmitInterval, cfg.SubmitBurst),
+		SubmitInterval: newRateLimiter(cfg.SubmitTaskInterval, 1),
+	}
+}
+
+// 429 を返す。Retry-After は秒単位で切り上げる
+func rateLimited(c echo.Context, retryAfter time.Duration) error {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Response().Header().Set("Retry-After", strconv.Itoa(seconds))
+	return newAPIError(http.StatusTooManyRequests, ErrCodeRateLimited, "too many requests").withDetails(map[string]int{
+		"retry_after": seconds,
+	})
+}
+
+// ルートごとに付けるミドルウェア。接続元の IP で数える
+func rateLimitByIP(l *rateLimiter) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if ok, retryAfter := l.allow(c.RealIP()); !ok {
+				return rateLimited(c, retryAfter)
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	registrationModeOpen      = "open"
+	registrationModeClosed    = "closed"
+	registrationModeCode      = "code"
+	registrationModeAllowlist = "allowlist"
+)
+
+func validRegistrationMode(mode string) bool {
+	return slices.Contains([]string{registrationModeOpen, registrationModeClosed, registrationModeCode, registrationModeAllowlist}, mode)
+}
+
+type RegistrationSettings struct {
+	Mode      string    `db:"mode"`
+	Code      string    `db:"code"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// 管理者が変更していればそれを、していなければ設定ファイルの値を返す
+func (s *Server) registrationSettings(ctx context.Context, st Store) (RegistrationSettings, error) {
+	settings, err := st.Registration().GetSettings(ctx)
+	if errors.Is(err, ErrNotFound) {
+		return RegistrationSettings{Mode: s.cfg.Registration.Mode, Code: s.cfg.Registration.Code}, nil
+	}
+	return settings, err
+}
+
+// 今の登録モードで req のユーザーを登録してよいか
+func (s *Server) checkRegistration(ctx context.Context, st Store, req RegisterRequest) error {
+	settings, err := s.registrationSettings(ctx, st)
+	if err != nil {
+		return internalError("failed to get registration settings", err)
+	}
+	switch settings.Mode {
+	case registrationModeOpen:
+		return nil
+	case registrationModeCode:
+		if subtle.ConstantTimeCompare([]byte(settings.Code), []byte(req.RegistrationCode)) != 1 {
+			return newAPIError(http.StatusForbidden, ErrCodeInvalidRegistrationCode, "invalid registration code")
+		}
+		return nil
+	case registrationModeAllowlist:
+		ok, err := st.Registration().IsAllowlisted(ctx, req.Name)
+		if err != nil {
+			return internalError("failed to check registration allowlist", err)
+		}
+		if !ok {
+			return newAPIError(http.StatusForbidden, ErrCodeNotAllowlisted, "this name is not allowed to register")
+		}
+		return nil
+	default:
+		return newAPIError(http.StatusForbidden, ErrCodeRegistrationClosed, "registration is closed")
+	}
+}
+
+type RegistrationResponse struct {
+	Mode string `json:"mode"`
+}
+
+// GET /api/registration
+// 登録フォームを出すか、登録コードの欄を出すかをフロントエンドが決めるのに使う
+func (s *Server) getRegistrationHandler(c echo.Context) error {
+	settings, err := s.registrationSettings(c.Request().Context(), s.store)
+	if err != nil {
+		return internalError("failed to get registration settings", err)
+	}
+	return c.JSON(http.StatusOK, RegistrationResponse{Mode: settings.Mode})
+}
+
+type AdminRegistrationResponse struct {
+	Mode      string `json:"mode"`
+	Code      string `json:"code"`
+	UpdatedAt int64  `json:"updated_at,omitempty"` // 設定ファイルの値のままなら 0
+}
+
+// GET /api/admin/registration
+func (s *Server) getAdminRegistrationHandler(c echo.Context) error {
+	if err := verifyAdminSession(c); err != nil {
+		return err
+	}
+	settings, err := s.registrationSettings(c.Request().Context(), s.store)
+	if err != nil {
+		return internalError("failed to get registration settings", err)
+	}
+	res := AdminRegistrationResponse{Mode: settings.Mode, Code: settings.Code}
+	if !settings.UpdatedAt.IsZero() {
+		res.UpdatedAt = settings.UpdatedAt.Unix()
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+type UpdateRegistrationRequest struct {
+	Mode string `json:"mode"`
+	Code string `json:"code"`
+}
+
+// POST /api/admin/registration
+func (s *Server) updateRegistrationHandler(c echo.Context) error {
+	defer c.Request().Body.Close()
+
+	if err := verifyAdminSession(c); err != nil {
+		return err
+	}
+	req := UpdateRegistrationRequest{}
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return newAPIError(http.StatusBadRequest, ErrCodeInvalidRequest, "failed to decode the request body as json")
+	}
+	errs := ValidationErrors{}
+	if !validRegistrationMode(req.Mode) {
+		errs.add("mode", "must be one of open, closed, code, allowlist")
+	}
+	if req.Mode == registrationModeCode && req.Code == "" {
+		errs.add("code", "must not be empty when mode is code")
+	} else if len(req.Code) > maxNameLength {
+		errs.add("code", fmt.Sprintf("must be at most %d bytes", maxNameLength))
+	}
+	if len(errs) > 0 {
+		return newAPIError(http.StatusBadRequest, ErrCodeValidationFailed, "invalid registration settings").withDetails(errs)
+	}
+
+	err := s.store.Registration().SaveSettings(c.Request().Context(), RegistrationSettings{Mode: req.Mode, Code: req.Code, UpdatedAt: dbNow()})
+	if err != nil {
+		return internalError("failed to save registration settings", err)
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// GET /api/admin/registration/allowlist
+func (s *Server) getRegistrationAllowlistHandler(c echo.Context) error {
+	if err := verifyAdminSession(c); err != nil {
+		return err
+	}
+	names, err := s.store.Registration().ListAllowlist(c.Request().Context())
+	if err != nil {
+		return internalError("failed to get registration allowlist", err)
+	}
+	return c.JSON(http.StatusOK, names)
+}
+
+type RegistrationAllowlistRequest struct {
+	Names []string `json:"names"`
+}
+
+func decodeRegistrationAllowlistRequest(c echo.Context) (RegistrationAllowlistRequest, error) {
+	req := RegistrationAllowlistRequest{}
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return req, newAPIError(http.StatusBadRequest, ErrCodeInvalidRequest, "failed to decode the request body as json")
+	}
+	errs := ValidationErrors{}
+	if len(req.Names) == 0 {
+		errs.add("names", "must not be empty")
+	}
+	for i, name := range req.Names {
+		validateUserName(&errs, fmt.Sprintf("names[%d]", i), name)
+	}
+	if len(errs) > 0 {
+		return req, newAPIError(http.StatusBadRequest, ErrCodeValidationFailed, "invalid names").withDetails(errs)
+	}
+	return req, nil
+}
+
+// POST /api/admin/registration/allowlist
+// mode が allowlist のときに登録できる名前を追加する
+func (s *Server) addRegistrationAllowlistHandler(c echo.Context) error {
+	defer c.Request().Body.Close()
+
+	if err := verifyAdminSession(c); err != nil {
+		return err
+	}
+	req, err := decodeRegistrationAllowlistRequest(c)
+	if err != nil {
+		return err
+	}
+	if err := s.store.Registration().AddAllowlist(c.Request().Context(), req.Names, dbNow()); err != nil {
+		return internalError("failed to add registration allowlist", err)
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// POST /api/admin/registration/allowlist/remove
+func (s *Server) removeRegistrationAllowlistHandler(c echo.Context) error {
+	defer c.Request().Body.Close()
+
+	if err := verifyAdminSession(c); err != nil {
+		return err
+	}
+	req, err := decodeRegistrationAllowlistRequest(c)
+	if err != nil {
+		return err
+	}
+	if err := s.store.Registration().RemoveAllowlist(c.Request().Context(), req.Names); err != nil {
+		return internalError("failed to remove registration allowlist", err)
+	}
+	return c.NoContent(http.StatusOK)
+}
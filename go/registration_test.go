@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCheckRegistration(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfgMode   string
+		settings  *RegistrationSettings
+		allowlist []string
+		req       RegisterRequest
+		wantCode  ErrorCode // 空なら登録できる
+	}{
+		{name: "open", cfgMode: registrationModeOpen, req: RegisterRequest{Name: "alice"}},
+		{name: "closed", cfgMode: registrationModeClosed, req: RegisterRequest{Name: "alice"}, wantCode: ErrCodeRegistrationClosed},
+		{name: "unknown mode is closed", cfgMode: "typo", req: RegisterRequest{Name: "alice"}, wantCode: ErrCodeRegistrationClosed},
+		{
+			name:     "admin setting overrides config",
+			cfgMode:  registrationModeOpen,
+			settings: &RegistrationSettings{Mode: registrationModeClosed},
+			req:      RegisterRequest{Name: "alice"},
+			wantCode: ErrCodeRegistrationClosed,
+		},
+		{
+			name:     "right code",
+			cfgMode:  registrationModeClosed,
+			settings: &RegistrationSettings{Mode: registrationModeCode, Code: "secret"},
+			req:      RegisterRequest{Name: "alice", RegistrationCode: "secret"},
+		},
+		{
+			name:     "wrong code",
+			cfgMode:  registrationModeClosed,
+			settings: &RegistrationSettings{Mode: registrationModeCode, Code: "secret"},
+			req:      RegisterRequest{Name: "alice", RegistrationCode: "secre"},
+			wantCode: ErrCodeInvalidRegistrationCode,
+		},
+		{
+			name:     "empty code",
+			cfgMode:  registrationModeClosed,
+			settings: &RegistrationSettings{Mode: registrationModeCode, Code: "secret"},
+			req:      RegisterRequest{Name: "alice"},
+			wantCode: ErrCodeInvalidRegistrationCode,
+		},
+		{
+			name:      "allowlisted",
+			cfgMode:   registrationModeAllowlist,
+			allowlist: []string{"alice"},
+			req:       RegisterRequest{Name: "alice"},
+		},
+		{
+			name:      "not allowlisted",
+			cfgMode:   registrationModeAllowlist,
+			allowlist: []string{"alice"},
+			req:       RegisterRequest{Name: "bob"},
+			wantCode:  ErrCodeNotAllowlisted,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeStore()
+			f.registration = tt.settings
+			f.allowlist = tt.allowlist
+			s := newTestServer(f)
+			s.cfg.Registration.Mode = tt.cfgMode
+
+			err := s.checkRegistration(context.Background(), f, tt.req)
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Fatalf("got %v, want nil", err)
+				}
+				return
+			}
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.Code != tt.wantCode {
+				t.Fatalf("got %v, want %s", err, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+	"github.com/labstack/echo-contrib/session"
+	"github.com/labstack/echo/v4"
+)
+
+// 期限切れのセッションを消す間隔
+const sessionPruneInterval = 10 * time.Minute
+
+// サーバー側に置くセッション。ID はクッキーの値 (トークン) の SHA-256
+type SessionRecord struct {
+	ID        string    `db:"id"`
+	UserName  string    `db:"user_name"`
+	Data      string    `db:"data"` // sess.Values を JSON にしたもの
+	IP        string    `db:"ip"`
+	UserAgent string    `db:"user_agent"`
+	CreatedAt time.Time `db:"created_at"`
+	ExpiresAt time.Time `db:"expires_at"`
+}
+
+// セッション・CSRF・API トークンに使う 32 バイトの乱数
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// gorilla/sessions の Store。クッキーには署名したトークンだけを入れ、中身は backend に置く
+// backend から消せばそのクッキーは使えなくなる
+type serverSessionStore struct {
+	backend     SessionStore
+	codec       *securecookie.SecureCookie
+	options     sessions.Options
+	ipExtractor echo.IPExtractor
+}
+
+func newServerSessionStore(backend SessionStore, cfg SessionConfig) *serverSessionStore {
+	return &serverSessionStore{
+		backend:     backend,
+		codec:       securecookie.New([]byte(cfg.Secret), nil).MaxAge(cfg.MaxAge),
+		options:     sessionOptions(cfg, cfg.MaxAge),
+		ipExtractor: echo.ExtractIPFromXFFHeader(),
+	}
+}
+
+func sessionOptions(cfg SessionConfig, maxAge int) sessions.Options {
+	return sessions.Options{
+		// /metrics や /debug/pprof でも管理者のセッションを使うので、/api/ 以下に限定しない
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   cfg.Secure,
+		SameSite: cfg.sameSite(),
+	}
+}
+
+func (st *serverSessionStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(st, name)
+}
+
+func (st *serverSessionStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	sess := sessions.NewSession(st, name)
+	opts := st.options
+	sess.Options = &opts
+	sess.IsNew = true
+
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return sess, nil
+	}
+	token := ""
+	if err := st.codec.Decode(name, cookie.Value, &token); err != nil {
+		// 以前のクッキーストアのクッキーや改ざんされたものは、ログインしていないものとして扱う
+		return sess, nil
+	}
+	record, err := st.backend.Get(r.Context(), hashSessionToken(token))
+	if errors.Is(err, ErrNotFound) {
+		return sess, nil
+	} else if err != nil {
+		return sess, err
+	}
+	if !record.ExpiresAt.After(dbNow()) {
+		return sess, nil
+	}
+	values := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(record.Data), &values); err != nil {
+		return sess, err
+	}
+	for k, v := range values {
+		sess.Values[k] = v
+	}
+	sess.ID = token
+	sess.IsNew = false
+	return sess, nil
+}
+
+func (st *serverSessionStore) Save(r *http.Request, w http.ResponseWriter, sess *sessions.Session) error {
+	ctx := r.Context()
+	if sess.Options.MaxAge < 0 {
+		if sess.ID != "" {
+			if err := st.backend.Delete(ctx, hashSessionToken(sess.ID)); err != nil {
+				return err
+			}
+		}
+		http.SetCookie(w, sessions.NewCookie(sess.Name(), "", sess.Options))
+		return nil
+	}
+
+	if sess.ID == "" {
+		token, err := randomToken()
+		if err != nil {
+			return err
+		}
+		sess.ID = token
+	}
+	values := map[string]interface{}{}
+	for k, v := range sess.Values {
+		values[fmt.Sprint(k)] = v
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	maxAge := sess.Options.MaxAge
+	if maxAge == 0 {
+		maxAge = st.options.MaxAge
+	}
+	userName, _ := sess.Values[defaultSessionUserNameKey].(string)
+	now := dbNow()
+	// 作成日時・IP・User-Agent は最初に保存したときのものが残る
+	err = st.backend.Save(ctx, SessionRecord{
+		ID:        hashSessionToken(sess.ID),
+		UserName:  userName,
+		Data:      string(data),
+		IP:        truncateString(st.ipExtractor(r), maxIPLength),
+		UserAgent: truncateString(r.UserAgent(), maxUserAgentLength),
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Duration(maxAge) * time.Second),
+	})
+	if err != nil {
+		return err
+	}
+
+	encoded, err := st.codec.Encode(sess.Name(), sess.ID)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, sessions.NewCookie(sess.Name(), encoded, sess.Options))
+	return nil
+}
+
+// ログインのたびに新しいトークンにする (セッション固定攻撃の対策)
+func (st *serverSessionStore) renew(ctx context.Context, sess *sessions.Session) error {
+	if sess.ID != "" {
+		if err := st.backend.Delete(ctx, hashSessionToken(sess.ID)); err != nil {
+			return err
+		}
+	}
+	sess.ID = ""
+	sess.Values = map[interface{}]interface{}{}
+	return nil
+}
+
+func (s *Server) pruneExpiredSessions() {
+	ticker := time.NewTicker(sessionPruneInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := s.sessions.backend.DeleteExpired(context.Background(), dbNow()); err != nil {
+			log.Printf("failed to prune sessions: %v", err)
+		}
+	}
+}
+
+// session.store: memory のときに使う。再起動すると全員ログアウトされる
+type memorySessionStore struct {
+	mu      sync.RWMutex
+	records map[string]SessionRecord
+}
+
+func newMemorySessionStore() *memorySessionStore {
+	return &memorySessionStore{records: map[string]SessionRecord{}}
+}
+
+func (m *memorySessionStore) Get(ctx context.Context, id string) (SessionRecord, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	r, ok := m.records[id]
+	if !ok {
+		return SessionRecord{}, ErrNotFound
+	}
+	return r, nil
+}
+
+func (m *memorySessionStore) Save(ctx context.Context, record SessionRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if old, ok := m.records[record.ID]; ok {
+		record.IP, record.UserAgent, record.CreatedAt = old.IP, old.UserAgent, old.CreatedAt
+	}
+	m.records[record.ID] = record
+	return nil
+}
+
+func (m *memorySessionStore) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	delete(m.records, id)
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *memorySessionStore) ListByUser(ctx context.Context, userName string) ([]SessionRecord, error) {
+	m.mu.RLock()
+	records := []SessionRecord{}
+	for _, r := range m.records {
+		if r.UserName == userName {
+			records = append(records, r)
+		}
+	}
+	m.mu.RUnlock()
+	sort.Slice(records, func(i, j int) bool { return records[i].CreatedAt.After(records[j].CreatedAt) })
+	return records, nil
+}
+
+func (m *memorySessionStore) DeleteByUser(ctx context.Context, userName string) error {
+	return m.deleteFunc(func(r SessionRecord) bool { return r.UserName == userName })
+}
+
+func (m *memorySessionStore) DeleteAll(ctx context.Context) error {
+	return m.deleteFunc(func(SessionRecord) bool { return true })
+}
+
+func (m *memorySessionStore) DeleteExpired(ctx context.Context, now time.Time) error {
+	return m.deleteFunc(func(r SessionRecord) bool { return !r.ExpiresAt.After(now) })
+}
+
+func (m *memorySessionStore) deleteFunc(match func(SessionRecord) bool) error {
+	m.mu.Lock()
+	for id, r := range m.records {
+		if match(r) {
+			delete(m.records, id)
+		}
+	}
+	m.mu.Unlock()
+	return nil
+}
+
+type SessionResponse struct {
+	ID        string `json:"id"`
+	IP        string `json:"ip"`
+	UserAgent string `json:"user_agent"`
+	CreatedAt int64  `json:"created_at"`
+	ExpiresAt int64  `json:"expires_at"`
+	Current   bool   `json:"current"`
+}
+
+// GET /api/account/sessions
+// 自分のログイン中のセッション
+func (s *Server) getAccountSessionsHandler(c echo.Context) error {
+	if err := verifyUserSession(c); err != nil {
+		return err
+	}
+	username, _ := loginUserName(c)
+	sess, _ := session.Get(defaultSessionIDKey, c)
+	current := hashSessionToken(sess.ID)
+
+	records, err := s.sessions.backend.ListByUser(c.Request().Context(), username)
+	if err != nil {
+		return internalError("failed to get sessions", err)
+	}
+	now := dbNow()
+	res := make([]SessionResponse, 0, len(records))
+	for _, r := range records {
+		if !r.ExpiresAt.After(now) {
+			continue
+		}
+		res = append(res, SessionResponse{
+			ID:        r.ID,
+			IP:        r.IP,
+			UserAgent: r.UserAgent,
+			CreatedAt: r.CreatedAt.Unix(),
+			ExpiresAt: r.ExpiresAt.Unix(),
+			Current:   r.ID == current,
+		})
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+type RevokeSessionRequest struct {
+	ID string `json:"id"`
+}
+
+// POST /api/account/sessions/revoke
+// 自分のセッションを 1 つログアウトさせる
+func (s *Server) revokeAccountSessionHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+	defer c.Request().Body.Close()
+
+	if err := verifyUserSession(c); err != nil {
+		return err
+	}
+	username, _ := loginUserName(c)
+
+	req := RevokeSessionRequest{}
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return newAPIError(http.StatusBadRequest, ErrCodeInvalidRequest, "failed to decode the request body as json")
+	}
+	record, err := s.sessions.backend.Get(ctx, req.ID)
+	if errors.Is(err, ErrNotFound) || (err == nil && record.UserName != username) {
+		// 他人のセッションがあるかどうかは教えない
+		return newAPIError(http.StatusNotFound, ErrCodeSessionNotFound, "session not found")
+	} else if err != nil {
+		return internalError("failed to get session", err)
+	}
+	if err := s.sessions.backend.Delete(ctx, req.ID); err != nil {
+		return internalError("failed to revoke session", err)
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+type RevokeUserSessionsRequest struct {
+	UserName string `json:"user_name"`
+}
+
+// POST /api/admin/sessions/revoke
+// 指定したユーザーのセッションをすべてログアウトさせる
+func (s *Server) revokeUserSessionsHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+	defer c.Request().Body.Close()
+
+	if err := verifyAdminSession(c); err != nil {
+		return err
+	}
+	req := RevokeUserSessionsRequest{}
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return newAPIError(http.StatusBadRequest, ErrCodeInvalidRequest, "failed to decode the request body as json")
+	}
+	if req.UserName == "" {
+		return newAPIError(http.StatusBadRequest, ErrCodeInvalidRequest, "user_name is required")
+	}
+	if err := s.sessions.backend.DeleteByUser(ctx, req.UserName); err != nil {
+		return internalError("failed to revoke sessions", err)
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// POST /api/admin/sessions/revoke-all
+// 全員をログアウトさせる (呼び出した管理者自身も含む)
+func (s *Server) revokeAllSessionsHandler(c echo.Context) error {
+	if err := verifyAdminSession(c); err != nil {
+		return err
+	}
+	if err := s.sessions.backend.DeleteAll(c.Request().Context()); err != nil {
+		return internalError("failed to revoke sessions", err)
+	}
+	return c.NoContent(http.StatusOK)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const sqliteSQLDir = "../sql/sqlite"
+
+// db.driver が sqlite3 のときに使う。小さなコンテストやローカルでの動作確認用
+func connectSQLite(path string) (*sqlx.DB, error) {
+	// _txlock=immediate: 書き込みトランザクション同士が途中で SQLITE_BUSY にならないようにする
+	return sqlx.Open("sqlite3", "file:"+path+"?_busy_timeout=5000&_journal_mode=WAL&_txlock=immediate")
+}
+
+// sql/init.sh の SQLite 版。スキーマはマイグレーションで作られている前提
+func initSQLite(ctx context.Context, db sqlx.ExecerContext) error {
+	query, err := os.ReadFile(sqliteSQLDir + "/01_initial_data.sql")
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, string(query))
+	return err
+}
+
+// 新しいファイルなら初期データを入れる
+func seedSQLiteIfEmpty(ctx context.Context, db *sqlx.DB) error {
+	users := 0
+	if err := db.GetContext(ctx, &users, "SELECT COUNT(*) FROM users"); err != nil {
+		return err
+	}
+	if users > 0 {
+		return nil
+	}
+	return initSQLite(ctx, db)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultProgressTeams = 10
+	maxProgressTeams     = 50
+)
+
+type ScoreProgressPoint struct {
+	Time  int64 `json:"time"`
+	Score int   `json:"score"` // この時点での合計点
+}
+
+type TeamScoreProgress struct {
+	Rank            int    `json:"rank"`
+	TeamName        string `json:"team_name"`
+	TeamDisplayName string `json:"team_display_name"`
+	TotalScore      int    `json:"total_score"`
+	// 点数が上がった時刻だけ。最初の得点より前は 0 点
+	Points []ScoreProgressPoint `json:"points"`
+}
+
+type ScoreProgressResponse struct {
+	Teams []TeamScoreProgress `json:"teams"` // 順位表の順
+}
+
+// GET /api/standings/progress?top=10 または ?teams=team1,team2
+// 順位表のグラフ用に、チームごとの合計点の推移を返す
+func (s *Server) getScoreProgressHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	top := defaultProgressTeams
+	if c.QueryParam("top") != "" {
+		n, err := strconv.Atoi(c.QueryParam("top"))
+		if err != nil || n < 1 || n > maxProgressTeams {
+			return newAPIError(http.StatusBadRequest, ErrCodeInvalidRequest, "top must be between 1 and "+strconv.Itoa(maxProgressTeams))
+		}
+		top = n
+	}
+	var names []string
+	if c.QueryParam("teams") != "" {
+		names = strings.Split(c.QueryParam("teams"), ",")
+		if len(names) > maxProgressTeams {
+			return newAPIError(http.StatusBadRequest, ErrCodeInvalidRequest, "too many teams")
+		}
+	}
+
+	// 順位表に出ていないチーム (hidden) は選べない
+	standings, err := s.getstandings(ctx)
+	if err != nil {
+		return internalError("failed to get standings", err)
+	}
+	selected := []TeamsStandings{}
+	if names == nil {
+		selected = standings.StandingsData[:min(top, len(standings.StandingsData))]
+	} else {
+		for _, name := range names {
+			i := slices.IndexFunc(standings.StandingsData, func(t TeamsStandings) bool { return t.TeamName == name })
+			if i < 0 {
+				return newAPIError(http.StatusBadRequest, ErrCodeTeamNotFound, "team "+name+" not found")
+			}
+			selected = append(selected, standings.StandingsData[i])
+		}
+	}
+
+	teams, err := s.store.Teams().List(ctx)
+	if err != nil {
+		return internalError("failed to get teams", err)
+	}
+	res := ScoreProgressResponse{Teams: make([]TeamScoreProgress, 0, len(selected))}
+	// user_id から res.Teams の添字
+	userTeam := map[int]int{}
+	filter := SubmissionFilter{ScoredOnly: true}
+	for i, t := range selected {
+		res.Teams = append(res.Teams, TeamScoreProgress{
+			Rank:            t.Rank,
+			TeamName:        t.TeamName,
+			TeamDisplayName: t.TeamDisplayName,
+			TotalScore:      t.TotalScore,
+			Points:          []ScoreProgressPoint{},
+		})
+		j := slices.IndexFunc(teams, func(team Team) bool { return team.Name == t.TeamName })
+		if j < 0 {
+			continue
+		}
+		for _, id := range []int{teams[j].LeaderID, teams[j].Member1ID, teams[j].Member2ID} {
+			if id != nulluserid {
+				userTeam[id] = i
+				filter.UserIDs = append(filter.UserIDs, id)
+			}
+		}
+	}
+	if len(filter.UserIDs) == 0 {
+		return c.JSON(http.StatusOK, res)
+	}
+
+	// 得点の付いた提出だけを 1 回で取り、古い順に足していく
+	submissions, err := s.store.Submissions().List(ctx, filter)
+	if err != nil {
+		return internalError("failed to get submissions", err)
+	}
+	slices.SortFunc(submissions, func(a, b Submission) int {
+		if n := a.SubmittedAt.Compare(b.SubmittedAt); n != 0 {
+			return n
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	// チームごとのサブタスクの最高点と、その合計
+	best := make([]map[int]int, len(res.Teams))
+	total := make([]int, len(res.Teams))
+	for _, submission := range submissions {
+		i := userTeam[submission.UserID]
+		if best[i] == nil {
+			best[i] = map[int]int{}
+		}
+		if submission.Score <= best[i][submission.SubTaskID] {
+			continue
+		}
+		total[i] += submission.Score - best[i][submission.SubTaskID]
+		best[i][submission.SubTaskID] = submission.Score
+
+		point := ScoreProgressPoint{Time: submission.SubmittedAt.Unix(), Score: total[i]}
+		points := res.Teams[i].Points
+		// 同じ秒の提出は 1 点にまとめる
+		if len(points) > 0 && points[len(points)-1].Time == point.Time {
+			points[len(points)-1] = point
+		} else {
+			res.Teams[i].Points = append(points, point)
+		}
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// 見つからなかったときは各ストアがこれを返す (sql.ErrNoRows は外に出さない)
+var ErrNotFound = errors.New("not found")
+
+type Store interface {
+	Users() UserStore
+	Teams() TeamStore
+	Tasks() TaskStore
+	Submissions() SubmissionStore
+	Logins() LoginStore
+	Sessions() SessionStore
+	APITokens() APITokenStore
+	Registration() RegistrationStore
+	FirstSolves() FirstSolveStore
+	// fn に渡されるストアはすべて同じトランザクションを使う。fn がエラーを返したらロールバックする
+	WithTx(ctx context.Context, fn func(Store) error) error
+	// 初期データを入れ直す。スキーマはそのままで、各テーブルの行を消してから入れる
+	// (schema_migrations 以外のテーブルはすべて空にするので、テーブルを足したら初期データの SQL にも足す)
+	Reset(ctx context.Context) error
+	// DB に繋がるかどうか (/readyz 用)
+	Ping(ctx context.Context) error
+}
+
+type UserStore interface {
+	GetByID(ctx context.Context, id int) (User, error)
+	GetByName(ctx context.Context, name string) (User, error)
+	Create(ctx context.Context, user User) error
+	// id で指定したユーザーの name・display_name・description・passhash・disabled を書き換える
+	Update(ctx context.Context, user User) error
+	// id 順
+	List(ctx context.Context, filter UserFilter) ([]User, error)
+	Count(ctx context.Context, filter UserFilter) (int, error)
+
+	// ユーザーごとに 1 つ。あれば上書きする
+	SavePasswordReset(ctx context.Context, reset PasswordReset) error
+	GetPasswordReset(ctx context.Context, tokenHash string) (PasswordReset, error)
+	DeletePasswordReset(ctx context.Context, userID int) error
+}
+
+type UserFilter struct {
+	Query  string // name か display_name に含まれる文字列。空なら絞り込まない
+	Offset int
+	Limit  int // 0 なら全件 (Count では使わない)
+}
+
+type TeamStore interface {
+	List(ctx context.Context) ([]Team, error)
+	GetByID(ctx context.Context, id int) (Team, error)
+	GetByName(ctx context.Context, name string) (Team, error)
+	// userID がリーダーかメンバーとして所属しているチーム
+	GetByMember(ctx context.Context, userID int) (Team, error)
+	Create(ctx context.Context, team Team) error
+	UpdateMembers(ctx context.Context, team Team) error
+	// 招待コード以外のカラムを書き換える
+	Update(ctx context.Context, team Team) error
+	Delete(ctx context.Context, id int) error
+}
+
+type TaskStore interface {
+	List(ctx context.Context) ([]Task, error)
+	GetByID(ctx context.Context, id int) (Task, error)
+	GetByName(ctx context.Context, name string) (Task, error)
+	Create(ctx context.Context, task Task) (int, error)
+
+	ListSubtasks(ctx context.Context, taskID int) ([]Subtask, error)
+	GetSubtask(ctx context.Context, id int) (Subtask, error)
+	GetSubtaskByName(ctx context.Context, taskID int, name string) (Subtask, error)
+	CreateSubtask(ctx context.Context, subtask Subtask) (int, error)
+
+	ListAnswers(ctx context.Context, subtaskID int) ([]Answer, error)
+	FindAnswer(ctx context.Context, taskID int, answer string) (Answer, error)
+	CreateAnswer(ctx context.Context, answer Answer) error
+	SubtaskMaxScore(ctx context.Context, subtaskID int) (int, error)
+	// サブタスクごとの満点を合計したもの
+	TaskMaxScore(ctx context.Context, taskID int) (int, error)
+}
+
+type SubmissionFilter struct {
+	TaskID         int    // 0 なら絞り込まない
+	UserID         int    // 0 なら絞り込まない
+	AnswerContains string // 空なら絞り込まない
+	UserIDs        []int  // 空なら絞り込まない
+	ScoredOnly     bool   // true なら得点の付いた提出だけ
+}
+
+type SubmissionStore interface {
+	List(ctx context.Context, filter SubmissionFilter) ([]Submission, error)
+	Create(ctx context.Context, submission Submission) error
+	UpdateScore(ctx context.Context, id int, subtaskID int, score int) error
+	DeleteAll(ctx context.Context) error
+	CountByUser(ctx context.Context, userID int) (int, error)
+	CountByTeam(ctx context.Context, taskID int, team Team) (int, error)
+	// サブタスクごとの最高点を合計したもの
+	TeamTaskScore(ctx context.Context, taskID int, team Team) (int, error)
+	TeamSubtaskScore(ctx context.Context, subtaskID int, team Team) (int, error)
+}
+
+type LoginStore interface {
+	CreateAttempt(ctx context.Context, attempt LoginAttempt) error
+	// 新しい順に limit 件
+	ListAttempts(ctx context.Context, userName string, limit int) ([]LoginAttempt, error)
+	// since より後で、かつ最後に成功したとき以降の失敗の数
+	CountFailuresSince(ctx context.Context, userName string, since time.Time) (int, error)
+
+	GetLockout(ctx context.Context, userName string) (LoginLockout, error)
+	// なければ作り、あれば上書きする
+	SaveLockout(ctx context.Context, lockout LoginLockout) error
+	// at の時点でロックされているもの
+	ListLockouts(ctx context.Context, at time.Time) ([]LoginLockout, error)
+	// 試行の記録とロックをすべて消す (アカウントの削除用)
+	DeleteByUser(ctx context.Context, userName string) error
+}
+
+// session.store が memory のときは memorySessionStore を使う
+type SessionStore interface {
+	Get(ctx context.Context, id string) (SessionRecord, error)
+	// なければ作り、あれば上書きする
+	Save(ctx context.Context, record SessionRecord) error
+	Delete(ctx context.Context, id string) error
+	ListByUser(ctx context.Context, userName string) ([]SessionRecord, error)
+	DeleteByUser(ctx context.Context, userName string) error
+	DeleteAll(ctx context.Context) error
+	DeleteExpired(ctx context.Context, now time.Time) error
+}
+
+type APITokenStore interface {
+	Create(ctx context.Context, token APIToken) (int, error)
+	GetByHash(ctx context.Context, tokenHash string) (APIToken, error)
+	ListByUser(ctx context.Context, userName string) ([]APIToken, error)
+	// 他人のトークンは消さない。消したら true
+	Delete(ctx context.Context, id int, userName string) (bool, error)
+	DeleteByUser(ctx context.Context, userName string) error
+}
+
+type RegistrationStore interface {
+	// 管理者がまだ変更していなければ ErrNotFound
+	GetSettings(ctx context.Context) (RegistrationSettings, error)
+	// なければ作り、あれば上書きする
+	SaveSettings(ctx context.Context, settings RegistrationSettings) error
+
+	ListAllowlist(ctx context.Context) ([]string, error)
+	IsAllowlisted(ctx context.Context, name string) (bool, error)
+	// すでにある名前は無視する
+	AddAllowlist(ctx context.Context, names []string, at time.Time) error
+	RemoveAllowlist(ctx context.Context, names []string) error
+}
+
+type FirstSolveStore interface {
+	// taskID が 0 なら全タスク
+	List(ctx context.Context, taskID int) ([]FirstSolve, error)
+	Get(ctx context.Context, subtaskID int) (FirstSolve, error)
+	// なければ作り、あれば fs の方が早いときだけ上書きする
+	// 読んでから書くと同時に解いたチームの後勝ちになるので、比べるのも 1 文で行う
+	SaveIfEarlier(ctx context.Context, fs FirstSolve) error
+	DeleteAll(ctx context.Context) error
+}
+
+// DB に保存する時刻。SQLite では文字列として比較されるので、タイムゾーンと精度をそろえておく
+func dbNow() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/gorilla/sessions"
+	"github.com/labstack/echo-contrib/session"
+	"github.com/labstack/echo/v4"
+)
+
+// テスト用のメモリ上の Store
+// テストで使うメソッドだけを実装する。それ以外は埋め込んだ nil のインターフェースを呼ぶので panic する
+type fakeStore struct {
+	Store
+	users        []User
+	teams        []Team
+	tasks        []Task
+	subtasks     []Subtask
+	answers      []Answer
+	submissions  []Submission
+	attempts     []LoginAttempt
+	lockouts     map[string]LoginLockout
+	registration *RegistrationSettings
+	allowlist    []string
+	firstSolves  map[int]FirstSolve
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		lockouts:    map[string]LoginLockout{},
+		firstSolves: map[int]FirstSolve{},
+	}
+}
+
+func (f *fakeStore) Users() UserStore                { return fakeUserStore{f: f} }
+func (f *fakeStore) Teams() TeamStore                { return fakeTeamStore{f: f} }
+func (f *fakeStore) Tasks() TaskStore                { return fakeTaskStore{f: f} }
+func (f *fakeStore) Submissions() SubmissionStore    { return fakeSubmissionStore{f: f} }
+func (f *fakeStore) Logins() LoginStore              { return fakeLoginStore{f: f} }
+func (f *fakeStore) Sessions() SessionStore          { return newMemorySessionStore() }
+func (f *fakeStore) Registration() RegistrationStore { return fakeRegistrationStore{f: f} }
+func (f *fakeStore) FirstSolves() FirstSolveStore    { return fakeFirstSolveStore{f: f} }
+
+// ロールバックはしない。テストではエラーになった後の中身を見ない
+func (f *fakeStore) WithTx(ctx context.Context, fn func(Store) error) error {
+	return fn(f)
+}
+
+func findFake[T any](items []T, match func(T) bool) (T, error) {
+	i := slices.IndexFunc(items, match)
+	if i < 0 {
+		var zero T
+		return zero, ErrNotFound
+	}
+	return items[i], nil
+}
+
+func isTeamMember(team Team, userID int) bool {
+	return team.LeaderID == userID || team.Member1ID == userID || team.Member2ID == userID
+}
+
+type fakeUserStore struct {
+	UserStore
+	f *fakeStore
+}
+
+func (s fakeUserStore) GetByID(ctx context.Context, id int) (User, error) {
+	return findFake(s.f.users, func(u User) bool { return u.ID == id })
+}
+
+func (s fakeUserStore) GetByName(ctx context.Context, name string) (User, error) {
+	return findFake(s.f.users, func(u User) bool { return u.Name == name })
+}
+
+type fakeTeamStore struct {
+	TeamStore
+	f *fakeStore
+}
+
+func (s fakeTeamStore) List(ctx context.Context) ([]Team, error) {
+	return slices.Clone(s.f.teams), nil
+}
+
+func (s fakeTeamStore) GetByID(ctx context.Context, id int) (Team, error) {
+	return findFake(s.f.teams, func(t Team) bool { return t.ID == id })
+}
+
+func (s fakeTeamStore) GetByMember(ctx context.Context, userID int) (Team, error) {
+	return findFake(s.f.teams, func(t Team) bool { return isTeamMember(t, userID) })
+}
+
+type fakeTaskStore struct {
+	TaskStore
+	f *fakeStore
+}
+
+func (s fakeTaskStore) List(ctx context.Context) ([]Task, error) {
+	return slices.Clone(s.f.tasks), nil
+}
+
+func (s fakeTaskStore) GetByName(ctx context.Context, name string) (Task, error) {
+	return findFake(s.f.tasks, func(t Task) bool { return t.Name == name })
+}
+
+func (s fakeTaskStore) ListSubtasks(ctx context.Context, taskID int) ([]Subtask, error) {
+	subtasks := []Subtask{}
+	for _, subtask := range s.f.subtasks {
+		if subtask.TaskID == taskID {
+			subtasks = append(subtasks, subtask)
+		}
+	}
+	return subtasks, nil
+}
+
+func (s fakeTaskStore) ListAnswers(ctx context.Context, subtaskID int) ([]Answer, error) {
+	answers := []Answer{}
+	for _, answer := range s.f.answers {
+		if answer.SubtaskID == subtaskID {
+			answers = append(answers, answer)
+		}
+	}
+	return answers, nil
+}
+
+func (s fakeTaskStore) SubtaskMaxScore(ctx context.Context, subtaskID int) (int, error) {
+	maxscore := 0
+	for _, answer := range s.f.answers {
+		if answer.SubtaskID == subtaskID {
+			maxscore = max(maxscore, answer.Score)
+		}
+	}
+	return maxscore, nil
+}
+
+func (s fakeTaskStore) TaskMaxScore(ctx context.Context, taskID int) (int, error) {
+	score := 0
+	for _, subtask := range s.f.subtasks {
+		if subtask.TaskID == taskID {
+			maxscore, _ := s.SubtaskMaxScore(ctx, subtask.ID)
+			score += maxscore
+		}
+	}
+	return score, nil
+}
+
+type fakeSubmissionStore struct {
+	SubmissionStore
+	f *fakeStore
+}
+
+// SQL と同じく submitted_at の新しい順。同じ時刻の順番は決めない (ここでは ID の小さい順) ので、呼ぶ側で並べ直す必要がある
+func (s fakeSubmissionStore) List(ctx context.Context, filter SubmissionFilter) ([]Submission, error) {
+	submissions := []Submission{}
+	for _, sub := range s.f.submissions {
+		if filter.TaskID != 0 && sub.TaskID != filter.TaskID {
+			continue
+		}
+		if filter.UserID != 0 && sub.UserID != filter.UserID {
+			continue
+		}
+		if filter.UserIDs != nil && !slices.Contains(filter.UserIDs, sub.UserID) {
+			continue
+		}
+		if filter.ScoredOnly && sub.Score <= 0 {
+			continue
+		}
+		submissions = append(submissions, sub)
+	}
+	slices.SortStableFunc(submissions, func(a, b Submission) int {
+		if n := b.SubmittedAt.Compare(a.SubmittedAt); n != 0 {
+			return n
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return submissions, nil
+}
+
+func (s fakeSubmissionStore) Create(ctx context.Context, submission Submission) error {
+	submission.ID = len(s.f.submissions) + 1
+	s.f.submissions = append(s.f.submissions, submission)
+	return nil
+}
+
+func (s fakeSubmissionStore) CountByTeam(ctx context.Context, taskID int, team Team) (int, error) {
+	count := 0
+	for _, sub := range s.f.submissions {
+		if sub.TaskID == taskID && isTeamMember(team, sub.UserID) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// サブタスクごと (不正解は subtask_id が -1) の最高点を合計する
+func (s fakeSubmissionStore) TeamTaskScore(ctx context.Context, taskID int, team Team) (int, error) {
+	best := map[int]int{}
+	for _, sub := range s.f.submissions {
+		if sub.TaskID == taskID && isTeamMember(team, sub.UserID) {
+			best[sub.SubTaskID] = max(best[sub.SubTaskID], sub.Score)
+		}
+	}
+	score := 0
+	for _, sc := range best {
+		score += sc
+	}
+	return score, nil
+}
+
+type fakeLoginStore struct {
+	LoginStore
+	f *fakeStore
+}
+
+func (s fakeLoginStore) CreateAttempt(ctx context.Context, attempt LoginAttempt) error {
+	attempt.ID = len(s.f.attempts) + 1
+	s.f.attempts = append(s.f.attempts, attempt)
+	return nil
+}
+
+// store_sql.go と同じく、最後に成功した後の失敗だけを数える
+func (s fakeLoginStore) CountFailuresSince(ctx context.Context, userName string, since time.Time) (int, error) {
+	from := since
+	for _, attempt := range s.f.attempts {
+		if attempt.UserName == userName && attempt.Succeeded && attempt.AttemptedAt.After(from) {
+			from = attempt.AttemptedAt
+		}
+	}
+	count := 0
+	for _, attempt := range s.f.attempts {
+		if attempt.UserName == userName && !attempt.Succeeded && attempt.AttemptedAt.After(since) && !attempt.AttemptedAt.Before(from) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s fakeLoginStore) GetLockout(ctx context.Context, userName string) (LoginLockout, error) {
+	lockout, ok := s.f.lockouts[userName]
+	if !ok {
+		return LoginLockout{}, ErrNotFound
+	}
+	return lockout, nil
+}
+
+func (s fakeLoginStore) SaveLockout(ctx context.Context, lockout LoginLockout) error {
+	s.f.lockouts[lockout.UserName] = lockout
+	return nil
+}
+
+type fakeRegistrationStore struct {
+	RegistrationStore
+	f *fakeStore
+}
+
+func (s fakeRegistrationStore) GetSettings(ctx context.Context) (RegistrationSettings, error) {
+	if s.f.registration == nil {
+		return RegistrationSettings{}, ErrNotFound
+	}
+	return *s.f.registration, nil
+}
+
+func (s fakeRegistrationStore) IsAllowlisted(ctx context.Context, name string) (bool, error) {
+	return slices.Contains(s.f.allowlist, name), nil
+}
+
+type fakeFirstSolveStore struct {
+	FirstSolveStore
+	f *fakeStore
+}
+
+func (s fakeFirstSolveStore) List(ctx context.Context, taskID int) ([]FirstSolve, error) {
+	solves := []FirstSolve{}
+	for _, fs := range s.f.firstSolves {
+		if taskID == 0 || fs.TaskID == taskID {
+			solves = append(solves, fs)
+		}
+	}
+	slices.SortFunc(solves, func(a, b FirstSolve) int { return cmp.Compare(a.SubtaskID, b.SubtaskID) })
+	return solves, nil
+}
+
+func (s fakeFirstSolveStore) Get(ctx context.Context, subtaskID int) (FirstSolve, error) {
+	fs, ok := s.f.firstSolves[subtaskID]
+	if !ok {
+		return FirstSolve{}, ErrNotFound
+	}
+	return fs, nil
+}
+
+func (s fakeFirstSolveStore) SaveIfEarlier(ctx context.Context, fs FirstSolve) error {
+	if prev, ok := s.f.firstSolves[fs.SubtaskID]; ok && !fs.SolvedAt.Before(prev.SolvedAt) {
+		return nil
+	}
+	s.f.firstSolves[fs.SubtaskID] = fs
+	return nil
+}
+
+func (s fakeFirstSolveStore) DeleteAll(ctx context.Context) error {
+	clear(s.f.firstSolves)
+	return nil
+}
+
+// userName でログインした状態で h を呼ぶ
+func withLogin(userName string, h echo.HandlerFunc) echo.HandlerFunc {
+	return session.Middleware(sessions.NewCookieStore([]byte("test")))(func(c echo.Context) error {
+		sess, err := session.Get(defaultSessionIDKey, c)
+		if err != nil {
+			return err
+		}
+		sess.Values[defaultSessionUserNameKey] = userName
+		return h(c)
+	})
+}
+
+// セッションはメモリに置き、DB は f を使うサーバー
+func newTestServer(f *fakeStore) *Server {
+	cfg := defaultConfig()
+	cfg.Session.Store = "memory"
+	return newServer(cfg, f)
+}
@@ -0,0 +1,605 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// MySQL と SQLite の両方で使う。*sqlx.DB と *sqlx.Tx のどちらでも動くようにする
+type sqlStore struct {
+	db   *sqlx.DB
+	conn sqlx.ExtContext
+}
+
+func newSQLStore(db *sqlx.DB) *sqlStore {
+	return &sqlStore{db: db, conn: db}
+}
+
+func (s *sqlStore) Users() UserStore                { return sqlUserStore{s.conn} }
+func (s *sqlStore) Teams() TeamStore                { return sqlTeamStore{s.conn} }
+func (s *sqlStore) Tasks() TaskStore                { return sqlTaskStore{s.conn} }
+func (s *sqlStore) Submissions() SubmissionStore    { return sqlSubmissionStore{s.conn} }
+func (s *sqlStore) Logins() LoginStore              { return sqlLoginStore{s.conn} }
+func (s *sqlStore) Sessions() SessionStore          { return sqlSessionStore{s.conn} }
+func (s *sqlStore) APITokens() APITokenStore        { return sqlAPITokenStore{s.conn} }
+func (s *sqlStore) Registration() RegistrationStore { return sqlRegistrationStore{s.conn} }
+func (s *sqlStore) FirstSolves() FirstSolveStore    { return sqlFirstSolveStore{s.conn} }
+
+func (s *sqlStore) WithTx(ctx context.Context, fn func(Store) error) error {
+	if _, ok := s.conn.(*sqlx.Tx); ok {
+		// すでにトランザクションの中にいる
+		return fn(s)
+	}
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(&sqlStore{db: s.db, conn: tx}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqlStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+func (s *sqlStore) Reset(ctx context.Context) error {
+	if s.db.DriverName() == "sqlite3" {
+		return initSQLite(ctx, s.conn)
+	}
+	if out, err := exec.CommandContext(ctx, "../sql/init.sh").CombinedOutput(); err != nil {
+		return fmt.Errorf("init.sh failed: %w: %s", err, string(out))
+	}
+	return nil
+}
+
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// チームのメンバーの user_id を IN (?,?,?) に渡す形で返す。空き枠は nulluserid のまま
+func teamUserIDs(team Team) []interface{} {
+	return []interface{}{team.LeaderID, team.Member1ID, team.Member2ID}
+}
+
+type sqlUserStore struct {
+	conn sqlx.ExtContext
+}
+
+func (s sqlUserStore) GetByID(ctx context.Context, id int) (User, error) {
+	user := User{}
+	err := sqlx.GetContext(ctx, s.conn, &user, "SELECT * FROM users WHERE id = ?", id)
+	return user, notFound(err)
+}
+
+func (s sqlUserStore) GetByName(ctx context.Context, name string) (User, error) {
+	user := User{}
+	err := sqlx.GetContext(ctx, s.conn, &user, "SELECT * FROM users WHERE name = ?", name)
+	return user, notFound(err)
+}
+
+func (s sqlUserStore) Create(ctx context.Context, user User) error {
+	_, err := s.conn.ExecContext(ctx, "INSERT INTO users (name, display_name, description, passhash) VALUES (?, ?, ?, ?)", user.Name, user.DisplayName, user.Description, user.Passhash)
+	return err
+}
+
+func (s sqlUserStore) Update(ctx context.Context, user User) error {
+	_, err := s.conn.ExecContext(ctx, "UPDATE users SET name = ?, display_name = ?, description = ?, passhash = ?, disabled = ? WHERE id = ?", user.Name, user.DisplayName, user.Description, user.Passhash, user.Disabled, user.ID)
+	return err
+}
+
+func (s sqlUserStore) where(filter UserFilter) (string, []interface{}) {
+	if filter.Query == "" {
+		return "", nil
+	}
+	if s.conn.DriverName() == "sqlite3" {
+		return " WHERE name LIKE '%' || ? || '%' OR display_name LIKE '%' || ? || '%'", []interface{}{filter.Query, filter.Query}
+	}
+	return " WHERE name LIKE CONCAT('%', ?, '%') OR display_name LIKE CONCAT('%', ?, '%')", []interface{}{filter.Query, filter.Query}
+}
+
+func (s sqlUserStore) List(ctx context.Context, filter UserFilter) ([]User, error) {
+	where, params := s.where(filter)
+	query := "SELECT * FROM users" + where + " ORDER BY id"
+	if filter.Limit > 0 {
+		query += " LIMIT ? OFFSET ?"
+		params = append(params, filter.Limit, filter.Offset)
+	}
+	users := []User{}
+	err := sqlx.SelectContext(ctx, s.conn, &users, query, params...)
+	return users, err
+}
+
+func (s sqlUserStore) Count(ctx context.Context, filter UserFilter) (int, error) {
+	where, params := s.where(filter)
+	count := 0
+	err := sqlx.GetContext(ctx, s.conn, &count, "SELECT COUNT(*) FROM users"+where, params...)
+	return count, err
+}
+
+func (s sqlUserStore) SavePasswordReset(ctx context.Context, reset PasswordReset) error {
+	query := "INSERT INTO password_resets (user_id, token_hash, created_at, expires_at) VALUES (?, ?, ?, ?) ON DUPLICATE KEY UPDATE token_hash = VALUES(token_hash), created_at = VALUES(created_at), expires_at = VALUES(expires_at)"
+	if s.conn.DriverName() == "sqlite3" {
+		query = "INSERT INTO password_resets (user_id, token_hash, created_at, expires_at) VALUES (?, ?, ?, ?) ON CONFLICT (user_id) DO UPDATE SET token_hash = excluded.token_hash, created_at = excluded.created_at, expires_at = excluded.expires_at"
+	}
+	_, err := s.conn.ExecContext(ctx, query, reset.UserID, reset.TokenHash, reset.CreatedAt, reset.ExpiresAt)
+	return err
+}
+
+func (s sqlUserStore) GetPasswordReset(ctx context.Context, tokenHash string) (PasswordReset, error) {
+	reset := PasswordReset{}
+	err := sqlx.GetContext(ctx, s.conn, &reset, "SELECT * FROM password_resets WHERE token_hash = ?", tokenHash)
+	return reset, notFound(err)
+}
+
+func (s sqlUserStore) DeletePasswordReset(ctx context.Context, userID int) error {
+	_, err := s.conn.ExecContext(ctx, "DELETE FROM password_resets WHERE user_id = ?", userID)
+	return err
+}
+
+type sqlTeamStore struct {
+	conn sqlx.ExtContext
+}
+
+func (s sqlTeamStore) List(ctx context.Context) ([]Team, error) {
+	teams := []Team{}
+	err := sqlx.SelectContext(ctx, s.conn, &teams, "SELECT * FROM teams ORDER BY name")
+	return teams, err
+}
+
+func (s sqlTeamStore) GetByID(ctx context.Context, id int) (Team, error) {
+	team := Team{}
+	err := sqlx.GetContext(ctx, s.conn, &team, "SELECT * FROM teams WHERE id = ?", id)
+	return team, notFound(err)
+}
+
+func (s sqlTeamStore) GetByName(ctx context.Context, name string) (Team, error) {
+	team := Team{}
+	err := sqlx.GetContext(ctx, s.conn, &team, "SELECT * FROM teams WHERE name = ?", name)
+	return team, notFound(err)
+}
+
+func (s sqlTeamStore) GetByMember(ctx context.Context, userID int) (Team, error) {
+	team := Team{}
+	err := sqlx.GetContext(ctx, s.conn, &team, "SELECT * FROM teams WHERE leader_id = ? OR member1_id = ? OR member2_id = ?", userID, userID, userID)
+	return team, notFound(err)
+}
+
+func (s sqlTeamStore) Create(ctx context.Context, team Team) error {
+	_, err := s.conn.ExecContext(ctx, "INSERT INTO teams (name, display_name, leader_id, member1_id, member2_id, description, invitation_code, status) VALUES (?, ?, ?, ?, ?, ?, ?, ?)", team.Name, team.DisplayName, team.LeaderID, team.Member1ID, team.Member2ID, team.Description, team.InvitationCode, team.Status)
+	return err
+}
+
+func (s sqlTeamStore) UpdateMembers(ctx context.Context, team Team) error {
+	_, err := s.conn.ExecContext(ctx, "UPDATE teams SET leader_id = ?, member1_id = ?, member2_id = ? WHERE id = ?", team.LeaderID, team.Member1ID, team.Member2ID, team.ID)
+	return err
+}
+
+func (s sqlTeamStore) Update(ctx context.Context, team Team) error {
+	_, err := s.conn.ExecContext(ctx, "UPDATE teams SET name = ?, display_name = ?, leader_id = ?, member1_id = ?, member2_id = ?, description = ?, status = ? WHERE id = ?", team.Name, team.DisplayName, team.LeaderID, team.Member1ID, team.Member2ID, team.Description, team.Status, team.ID)
+	return err
+}
+
+func (s sqlTeamStore) Delete(ctx context.Context, id int) error {
+	_, err := s.conn.ExecContext(ctx, "DELETE FROM teams WHERE id = ?", id)
+	return err
+}
+
+type sqlTaskStore struct {
+	conn sqlx.ExtContext
+}
+
+func (s sqlTaskStore) List(ctx context.Context) ([]Task, error) {
+	tasks := []Task{}
+	err := sqlx.SelectContext(ctx, s.conn, &tasks, "SELECT * FROM tasks ORDER BY name")
+	return tasks, err
+}
+
+func (s sqlTaskStore) GetByID(ctx context.Context, id int) (Task, error) {
+	task := Task{}
+	err := sqlx.GetContext(ctx, s.conn, &task, "SELECT * FROM tasks WHERE id = ?", id)
+	return task, notFound(err)
+}
+
+func (s sqlTaskStore) GetByName(ctx context.Context, name string) (Task, error) {
+	task := Task{}
+	err := sqlx.GetContext(ctx, s.conn, &task, "SELECT * FROM tasks WHERE name = ?", name)
+	return task, notFound(err)
+}
+
+func (s sqlTaskStore) Create(ctx context.Context, task Task) (int, error) {
+	res, err := s.conn.ExecContext(ctx, "INSERT INTO tasks (name, display_name, statement, submission_limit) VALUES (?, ?, ?, ?)", task.Name, task.DisplayName, task.Statement, task.SubmissionLimit)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+func (s sqlTaskStore) ListSubtasks(ctx context.Context, taskID int) ([]Subtask, error) {
+	subtasks := []Subtask{}
+	err := sqlx.SelectContext(ctx, s.conn, &subtasks, "SELECT * FROM subtasks WHERE task_id = ?", taskID)
+	return subtasks, err
+}
+
+func (s sqlTaskStore) GetSubtask(ctx context.Context, id int) (Subtask, error) {
+	subtask := Subtask{}
+	err := sqlx.GetContext(ctx, s.conn, &subtask, "SELECT * FROM subtasks WHERE id = ?", id)
+	return subtask, notFound(err)
+}
+
+func (s sqlTaskStore) GetSubtaskByName(ctx context.Context, taskID int, name string) (Subtask, error) {
+	subtask := Subtask{}
+	err := sqlx.GetContext(ctx, s.conn, &subtask, "SELECT * FROM subtasks WHERE task_id = ? AND name = ?", taskID, name)
+	return subtask, notFound(err)
+}
+
+func (s sqlTaskStore) CreateSubtask(ctx context.Context, subtask Subtask) (int, error) {
+	res, err := s.conn.ExecContext(ctx, "INSERT INTO subtasks (name, display_name, task_id, statement) VALUES (?, ?, ?, ?)", subtask.Name, subtask.DisplayName, subtask.TaskID, subtask.Statement)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+func (s sqlTaskStore) ListAnswers(ctx context.Context, subtaskID int) ([]Answer, error) {
+	answers := []Answer{}
+	err := sqlx.SelectContext(ctx, s.conn, &answers, "SELECT * FROM answers WHERE subtask_id = ?", subtaskID)
+	return answers, err
+}
+
+func (s sqlTaskStore) FindAnswer(ctx context.Context, taskID int, answer string) (Answer, error) {
+	ans := Answer{}
+	err := sqlx.GetContext(ctx, s.conn, &ans, "SELECT * FROM answers WHERE task_id = ? AND answer = ?", taskID, answer)
+	return ans, notFound(err)
+}
+
+func (s sqlTaskStore) CreateAnswer(ctx context.Context, answer Answer) error {
+	_, err := s.conn.ExecContext(ctx, "INSERT INTO answers (task_id, subtask_id, answer, score) VALUES (?, ?, ?, ?)", answer.TaskID, answer.SubtaskID, answer.Answer, answer.Score)
+	return err
+}
+
+func (s sqlTaskStore) SubtaskMaxScore(ctx context.Context, subtaskID int) (int, error) {
+	maxscore := 0
+	err := sqlx.GetContext(ctx, s.conn, &maxscore, "SELECT COALESCE(MAX(score),0) FROM answers WHERE subtask_id = ?", subtaskID)
+	return maxscore, err
+}
+
+func (s sqlTaskStore) TaskMaxScore(ctx context.Context, taskID int) (int, error) {
+	sc := []int{}
+	if err := sqlx.SelectContext(ctx, s.conn, &sc, "SELECT MAX(score) FROM answers WHERE task_id = ? GROUP BY subtask_id", taskID); err != nil {
+		return 0, err
+	}
+	maxscore := 0
+	for _, s := range sc {
+		maxscore += s
+	}
+	return maxscore, nil
+}
+
+type sqlSubmissionStore struct {
+	conn sqlx.ExtContext
+}
+
+func (s sqlSubmissionStore) List(ctx context.Context, filter SubmissionFilter) ([]Submission, error) {
+	conditions := make([]string, 0)
+	params := make([]interface{}, 0)
+	if filter.TaskID != 0 {
+		conditions = append(conditions, "task_id = ?")
+		params = append(params, filter.TaskID)
+	}
+	if filter.UserID != 0 {
+		conditions = append(conditions, "user_id = ?")
+		params = append(params, filter.UserID)
+	}
+	if filter.AnswerContains != "" {
+		if s.conn.DriverName() == "sqlite3" {
+			conditions = append(conditions, "answer LIKE '%' || ? || '%'")
+		} else {
+			conditions = append(conditions, "answer LIKE CONCAT('%', ?, '%')")
+		}
+		params = append(params, filter.AnswerContains)
+	}
+	if len(filter.UserIDs) > 0 {
+		subconditions := make([]string, 0, len(filter.UserIDs))
+		for _, id := range filter.UserIDs {
+			subconditions = append(subconditions, "user_id = ?")
+			params = append(params, id)
+		}
+		conditions = append(conditions, "("+strings.Join(subconditions, " OR ")+")")
+	}
+	if filter.ScoredOnly {
+		conditions = append(conditions, "score > 0")
+	}
+
+	query := "SELECT * FROM submissions"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY submitted_at DESC"
+
+	submissions := []Submission{}
+	err := sqlx.SelectContext(ctx, s.conn, &submissions, query, params...)
+	return submissions, err
+}
+
+func (s sqlSubmissionStore) Create(ctx context.Context, submission Submission) error {
+	_, err := s.conn.ExecContext(ctx, "INSERT INTO submissions (task_id, user_id, submitted_at, answer, subtask_id, score, received_at) VALUES (?, ?, ?, ?, ?, ?, ?)", submission.TaskID, submission.UserID, submission.SubmittedAt, submission.Answer, submission.SubTaskID, submission.Score, submission.ReceivedAt)
+	return err
+}
+
+func (s sqlSubmissionStore) UpdateScore(ctx context.Context, id int, subtaskID int, score int) error {
+	_, err := s.conn.ExecContext(ctx, "UPDATE submissions SET score = ? , subtask_id = ? WHERE id = ?", score, subtaskID, id)
+	return err
+}
+
+func (s sqlSubmissionStore) DeleteAll(ctx context.Context) error {
+	_, err := s.conn.ExecContext(ctx, "DELETE FROM submissions")
+	return err
+}
+
+func (s sqlSubmissionStore) CountByUser(ctx context.Context, userID int) (int, error) {
+	count := 0
+	err := sqlx.GetContext(ctx, s.conn, &count, "SELECT COUNT(*) FROM submissions WHERE user_id = ?", userID)
+	return count, err
+}
+
+func (s sqlSubmissionStore) CountByTeam(ctx context.Context, taskID int, team Team) (int, error) {
+	count := 0
+	err := sqlx.GetContext(ctx, s.conn, &count, "SELECT COUNT(*) FROM submissions WHERE task_id = ? AND user_id IN (?,?,?)", append([]interface{}{taskID}, teamUserIDs(team)...)...)
+	return count, err
+}
+
+func (s sqlSubmissionStore) TeamTaskScore(ctx context.Context, taskID int, team Team) (int, error) {
+	sc := []int{}
+	if err := sqlx.SelectContext(ctx, s.conn, &sc, "SELECT MAX(score) FROM submissions WHERE task_id = ? AND user_id IN (?,?,?) GROUP BY subtask_id", append([]interface{}{taskID}, teamUserIDs(team)...)...); err != nil {
+		return 0, err
+	}
+	score := 0
+	for _, s := range sc {
+		score += s
+	}
+	return score, nil
+}
+
+func (s sqlSubmissionStore) TeamSubtaskScore(ctx context.Context, subtaskID int, team Team) (int, error) {
+	score := 0
+	err := sqlx.GetContext(ctx, s.conn, &score, "SELECT COALESCE(MAX(score),0) FROM submissions WHERE subtask_id = ? AND user_id IN (?,?,?)", append([]interface{}{subtaskID}, teamUserIDs(team)...)...)
+	return score, err
+}
+
+type sqlLoginStore struct {
+	conn sqlx.ExtContext
+}
+
+func (s sqlLoginStore) CreateAttempt(ctx context.Context, attempt LoginAttempt) error {
+	_, err := s.conn.ExecContext(ctx, "INSERT INTO login_attempts (user_name, ip, user_agent, succeeded, reason, attempted_at) VALUES (?, ?, ?, ?, ?, ?)", attempt.UserName, attempt.IP, attempt.UserAgent, attempt.Succeeded, attempt.Reason, attempt.AttemptedAt)
+	return err
+}
+
+func (s sqlLoginStore) ListAttempts(ctx context.Context, userName string, limit int) ([]LoginAttempt, error) {
+	attempts := []LoginAttempt{}
+	err := sqlx.SelectContext(ctx, s.conn, &attempts, "SELECT * FROM login_attempts WHERE user_name = ? ORDER BY attempted_at DESC, id DESC LIMIT ?", userName, limit)
+	return attempts, err
+}
+
+func (s sqlLoginStore) CountFailuresSince(ctx context.Context, userName string, since time.Time) (int, error) {
+	count := 0
+	err := sqlx.GetContext(ctx, s.conn, &count, "SELECT COUNT(*) FROM login_attempts WHERE user_name = ? AND succeeded = FALSE AND attempted_at > ? AND attempted_at >= COALESCE((SELECT MAX(attempted_at) FROM login_attempts WHERE user_name = ? AND succeeded = TRUE), ?)", userName, since, userName, since)
+	return count, err
+}
+
+func (s sqlLoginStore) GetLockout(ctx context.Context, userName string) (LoginLockout, error) {
+	lockout := LoginLockout{}
+	err := sqlx.GetContext(ctx, s.conn, &lockout, "SELECT * FROM login_lockouts WHERE user_name = ?", userName)
+	return lockout, notFound(err)
+}
+
+func (s sqlLoginStore) SaveLockout(ctx context.Context, lockout LoginLockout) error {
+	query := "INSERT INTO login_lockouts (user_name, locked_until, updated_at) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE locked_until = VALUES(locked_until), updated_at = VALUES(updated_at)"
+	if s.conn.DriverName() == "sqlite3" {
+		query = "INSERT INTO login_lockouts (user_name, locked_until, updated_at) VALUES (?, ?, ?) ON CONFLICT (user_name) DO UPDATE SET locked_until = excluded.locked_until, updated_at = excluded.updated_at"
+	}
+	_, err := s.conn.ExecContext(ctx, query, lockout.UserName, lockout.LockedUntil, lockout.UpdatedAt)
+	return err
+}
+
+func (s sqlLoginStore) ListLockouts(ctx context.Context, at time.Time) ([]LoginLockout, error) {
+	lockouts := []LoginLockout{}
+	err := sqlx.SelectContext(ctx, s.conn, &lockouts, "SELECT * FROM login_lockouts WHERE locked_until > ? ORDER BY locked_until DESC", at)
+	return lockouts, err
+}
+
+func (s sqlLoginStore) DeleteByUser(ctx context.Context, userName string) error {
+	if _, err := s.conn.ExecContext(ctx, "DELETE FROM login_attempts WHERE user_name = ?", userName); err != nil {
+		return err
+	}
+	_, err := s.conn.ExecContext(ctx, "DELETE FROM login_lockouts WHERE user_name = ?", userName)
+	return err
+}
+
+type sqlSessionStore struct {
+	conn sqlx.ExtContext
+}
+
+func (s sqlSessionStore) Get(ctx context.Context, id string) (SessionRecord, error) {
+	record := SessionRecord{}
+	err := sqlx.GetContext(ctx, s.conn, &record, "SELECT * FROM sessions WHERE id = ?", id)
+	return record, notFound(err)
+}
+
+func (s sqlSessionStore) Save(ctx context.Context, r SessionRecord) error {
+	query := "INSERT INTO sessions (id, user_name, data, ip, user_agent, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE user_name = VALUES(user_name), data = VALUES(data), expires_at = VALUES(expires_at)"
+	if s.conn.DriverName() == "sqlite3" {
+		query = "INSERT INTO sessions (id, user_name, data, ip, user_agent, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?) ON CONFLICT (id) DO UPDATE SET user_name = excluded.user_name, data = excluded.data, expires_at = excluded.expires_at"
+	}
+	_, err := s.conn.ExecContext(ctx, query, r.ID, r.UserName, r.Data, r.IP, r.UserAgent, r.CreatedAt, r.ExpiresAt)
+	return err
+}
+
+func (s sqlSessionStore) Delete(ctx context.Context, id string) error {
+	_, err := s.conn.ExecContext(ctx, "DELETE FROM sessions WHERE id = ?", id)
+	return err
+}
+
+func (s sqlSessionStore) ListByUser(ctx context.Context, userName string) ([]SessionRecord, error) {
+	records := []SessionRecord{}
+	err := sqlx.SelectContext(ctx, s.conn, &records, "SELECT * FROM sessions WHERE user_name = ? ORDER BY created_at DESC", userName)
+	return records, err
+}
+
+func (s sqlSessionStore) DeleteByUser(ctx context.Context, userName string) error {
+	_, err := s.conn.ExecContext(ctx, "DELETE FROM sessions WHERE user_name = ?", userName)
+	return err
+}
+
+func (s sqlSessionStore) DeleteAll(ctx context.Context) error {
+	_, err := s.conn.ExecContext(ctx, "DELETE FROM sessions")
+	return err
+}
+
+func (s sqlSessionStore) DeleteExpired(ctx context.Context, now time.Time) error {
+	_, err := s.conn.ExecContext(ctx, "DELETE FROM sessions WHERE expires_at <= ?", now)
+	return err
+}
+
+type sqlAPITokenStore struct {
+	conn sqlx.ExtContext
+}
+
+func (s sqlAPITokenStore) Create(ctx context.Context, token APIToken) (int, error) {
+	res, err := s.conn.ExecContext(ctx, "INSERT INTO api_tokens (user_name, name, token_hash, scopes, created_at) VALUES (?, ?, ?, ?, ?)", token.UserName, token.Name, token.TokenHash, token.Scopes, token.CreatedAt)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+func (s sqlAPITokenStore) GetByHash(ctx context.Context, tokenHash string) (APIToken, error) {
+	token := APIToken{}
+	err := sqlx.GetContext(ctx, s.conn, &token, "SELECT * FROM api_tokens WHERE token_hash = ?", tokenHash)
+	return token, notFound(err)
+}
+
+func (s sqlAPITokenStore) ListByUser(ctx context.Context, userName string) ([]APIToken, error) {
+	tokens := []APIToken{}
+	err := sqlx.SelectContext(ctx, s.conn, &tokens, "SELECT * FROM api_tokens WHERE user_name = ? ORDER BY id DESC", userName)
+	return tokens, err
+}
+
+func (s sqlAPITokenStore) Delete(ctx context.Context, id int, userName string) (bool, error) {
+	res, err := s.conn.ExecContext(ctx, "DELETE FROM api_tokens WHERE id = ? AND user_name = ?", id, userName)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (s sqlAPITokenStore) DeleteByUser(ctx context.Context, userName string) error {
+	_, err := s.conn.ExecContext(ctx, "DELETE FROM api_tokens WHERE user_name = ?", userName)
+	return err
+}
+
+type sqlRegistrationStore struct {
+	conn sqlx.ExtContext
+}
+
+func (s sqlRegistrationStore) GetSettings(ctx context.Context) (RegistrationSettings, error) {
+	settings := RegistrationSettings{}
+	err := sqlx.GetContext(ctx, s.conn, &settings, "SELECT mode, code, updated_at FROM registration_settings WHERE id = 1")
+	return settings, notFound(err)
+}
+
+func (s sqlRegistrationStore) SaveSettings(ctx context.Context, settings RegistrationSettings) error {
+	query := "INSERT INTO registration_settings (id, mode, code, updated_at) VALUES (1, ?, ?, ?) ON DUPLICATE KEY UPDATE mode = VALUES(mode), code = VALUES(code), updated_at = VALUES(updated_at)"
+	if s.conn.DriverName() == "sqlite3" {
+		query = "INSERT INTO registration_settings (id, mode, code, updated_at) VALUES (1, ?, ?, ?) ON CONFLICT (id) DO UPDATE SET mode = excluded.mode, code = excluded.code, updated_at = excluded.updated_at"
+	}
+	_, err := s.conn.ExecContext(ctx, query, settings.Mode, settings.Code, settings.UpdatedAt)
+	return err
+}
+
+func (s sqlRegistrationStore) ListAllowlist(ctx context.Context) ([]string, error) {
+	names := []string{}
+	err := sqlx.SelectContext(ctx, s.conn, &names, "SELECT name FROM registration_allowlist ORDER BY name")
+	return names, err
+}
+
+func (s sqlRegistrationStore) IsAllowlisted(ctx context.Context, name string) (bool, error) {
+	count := 0
+	err := sqlx.GetContext(ctx, s.conn, &count, "SELECT COUNT(*) FROM registration_allowlist WHERE name = ?", name)
+	return count > 0, err
+}
+
+func (s sqlRegistrationStore) AddAllowlist(ctx context.Context, names []string, at time.Time) error {
+	query := "INSERT IGNORE INTO registration_allowlist (name, created_at) VALUES (?, ?)"
+	if s.conn.DriverName() == "sqlite3" {
+		query = "INSERT OR IGNORE INTO registration_allowlist (name, created_at) VALUES (?, ?)"
+	}
+	for _, name := range names {
+		if _, err := s.conn.ExecContext(ctx, query, name, at); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s sqlRegistrationStore) RemoveAllowlist(ctx context.Context, names []string) error {
+	for _, name := range names {
+		if _, err := s.conn.ExecContext(ctx, "DELETE FROM registration_allowlist WHERE name = ?", name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type sqlFirstSolveStore struct {
+	conn sqlx.ExtContext
+}
+
+func (s sqlFirstSolveStore) List(ctx context.Context, taskID int) ([]FirstSolve, error) {
+	solves := []FirstSolve{}
+	var err error
+	if taskID == 0 {
+		err = sqlx.SelectContext(ctx, s.conn, &solves, "SELECT * FROM first_solves ORDER BY subtask_id")
+	} else {
+		err = sqlx.SelectContext(ctx, s.conn, &solves, "SELECT * FROM first_solves WHERE task_id = ? ORDER BY subtask_id", taskID)
+	}
+	return solves, err
+}
+
+func (s sqlFirstSolveStore) Get(ctx context.Context, subtaskID int) (FirstSolve, error) {
+	fs := FirstSolve{}
+	err := sqlx.GetContext(ctx, s.conn, &fs, "SELECT * FROM first_solves WHERE subtask_id = ?", subtaskID)
+	return fs, notFound(err)
+}
+
+func (s sqlFirstSolveStore) SaveIfEarlier(ctx context.Context, fs FirstSolve) error {
+	// MySQL は左から順に代入するので、solved_at は最後に書き換える
+	query := "INSERT INTO first_solves (subtask_id, task_id, team_id, user_id, solved_at) VALUES (?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE team_id = IF(VALUES(solved_at) < solved_at, VALUES(team_id), team_id), user_id = IF(VALUES(solved_at) < solved_at, VALUES(user_id), user_id), solved_at = LEAST(solved_at, VALUES(solved_at))"
+	if s.conn.DriverName() == "sqlite3" {
+		query = "INSERT INTO first_solves (subtask_id, task_id, team_id, user_id, solved_at) VALUES (?, ?, ?, ?, ?) ON CONFLICT (subtask_id) DO UPDATE SET team_id = excluded.team_id, user_id = excluded.user_id, solved_at = excluded.solved_at WHERE excluded.solved_at < first_solves.solved_at"
+	}
+	_, err := s.conn.ExecContext(ctx, query, fs.SubtaskID, fs.TaskID, fs.TeamID, fs.UserID, fs.SolvedAt)
+	return err
+}
+
+func (s sqlFirstSolveStore) DeleteAll(ctx context.Context) error {
+	_, err := s.conn.ExecContext(ctx, "DELETE FROM first_solves")
+	return err
+}
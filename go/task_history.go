@@ -0,0 +1,142 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/labstack/echo/v4"
+)
+
+type TaskHistoryEntry struct {
+	SubmittedAt        int64  `json:"submitted_at"`
+	UserName           string `json:"user_name"`
+	UserDisplayName    string `json:"user_display_name"`
+	Answer             string `json:"answer"`
+	SubtaskName        string `json:"subtask_name,omitempty"`
+	SubtaskDisplayName string `json:"subtask_display_name,omitempty"`
+	Score              int    `json:"score"`
+	// この提出を終えた時点でのタスクの得点
+	TaskScore int  `json:"task_score"`
+	Improved  bool `json:"improved"`
+}
+
+type SubtaskHistory struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	MaxScore    int    `json:"max_score"`
+	Score       int    `json:"score"`
+	// 満点を取った最初の提出の時刻。まだなら 0
+	SolvedAt int64 `json:"solved_at,omitempty"`
+}
+
+type TaskHistoryResponse struct {
+	TaskName        string             `json:"task_name"`
+	TaskDisplayName string             `json:"task_display_name"`
+	MaxScore        int                `json:"max_score"`
+	Score           int                `json:"score"`
+	Subtasks        []SubtaskHistory   `json:"subtasks"`
+	Submissions     []TaskHistoryEntry `json:"submissions"` // 古い順
+}
+
+// GET /api/tasks/:taskname/history
+// チームのこのタスクへの提出を古い順に並べ、それぞれの提出の後の得点を付ける
+func (s *Server) getTaskHistoryHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	if err := verifyUserSession(c); err != nil {
+		return err
+	}
+	team, inteam, err := s.getLoginTeam(c)
+	if err != nil {
+		return asAPIError("failed to get team", err)
+	}
+	if !inteam {
+		return newAPIError(http.StatusBadRequest, ErrCodeNotInTeam, "you have not joined team")
+	}
+
+	task, err := s.store.Tasks().GetByName(ctx, c.Param("taskname"))
+	if errors.Is(err, ErrNotFound) {
+		return newAPIError(http.StatusNotFound, ErrCodeTaskNotFound, "task not found")
+	} else if err != nil {
+		return internalError("failed to get task", err)
+	}
+	subtasks, err := s.getSubtasks(ctx, task.ID)
+	if err != nil {
+		return internalError("failed to get subtasks", err)
+	}
+
+	res := TaskHistoryResponse{
+		TaskName:        task.Name,
+		TaskDisplayName: task.DisplayName,
+		Subtasks:        make([]SubtaskHistory, 0, len(subtasks)),
+		Submissions:     []TaskHistoryEntry{},
+	}
+	// subtask_id から res.Subtasks の添字
+	subtaskIndex := map[int]int{}
+	for i, subtask := range subtasks {
+		maxscore, err := s.getSubtaskMaxScore(ctx, subtask.ID)
+		if err != nil {
+			return internalError("failed to get subtask score", err)
+		}
+		res.Subtasks = append(res.Subtasks, SubtaskHistory{
+			Name:        subtask.Name,
+			DisplayName: subtask.DisplayName,
+			MaxScore:    maxscore,
+		})
+		res.MaxScore += maxscore
+		subtaskIndex[subtask.ID] = i
+	}
+
+	filter := SubmissionFilter{TaskID: task.ID, UserIDs: []int{team.LeaderID}}
+	if team.Member1ID != nulluserid {
+		filter.UserIDs = append(filter.UserIDs, team.Member1ID)
+	}
+	if team.Member2ID != nulluserid {
+		filter.UserIDs = append(filter.UserIDs, team.Member2ID)
+	}
+	submissions, err := s.store.Submissions().List(ctx, filter)
+	if err != nil {
+		return internalError("failed to get submissions", err)
+	}
+	// List は新しい順なので、同じ時刻の提出は ID で並べ直す
+	slices.SortFunc(submissions, func(a, b Submission) int {
+		if n := a.SubmittedAt.Compare(b.SubmittedAt); n != 0 {
+			return n
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	for _, submission := range submissions {
+		user, err := s.getUserByID(ctx, submission.UserID)
+		if err != nil {
+			return internalError("failed to get user", err)
+		}
+		entry := TaskHistoryEntry{
+			SubmittedAt:     submission.SubmittedAt.Unix(),
+			UserName:        user.Name,
+			UserDisplayName: user.DisplayName,
+			Answer:          submission.Answer,
+			Score:           submission.Score,
+		}
+		// 不正解の提出は subtask_id が -1
+		if i, ok := subtaskIndex[submission.SubTaskID]; ok {
+			subtask := &res.Subtasks[i]
+			entry.SubtaskName = subtask.Name
+			entry.SubtaskDisplayName = subtask.DisplayName
+			if submission.Score > subtask.Score {
+				res.Score += submission.Score - subtask.Score
+				subtask.Score = submission.Score
+				entry.Improved = true
+			}
+			if subtask.SolvedAt == 0 && solvesSubtask(subtask.Score, subtask.MaxScore) {
+				subtask.SolvedAt = entry.SubmittedAt
+			}
+		}
+		entry.TaskScore = res.Score
+		res.Submissions = append(res.Submissions, entry)
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
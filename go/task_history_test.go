@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// タスク 1 にサブタスク 1 (満点 100) と 2 (満点 50)
+// alice と bob がチーム alpha、carol はチーム beta
+func newTaskHistoryStore() *fakeStore {
+	f := newFakeStore()
+	f.users = []User{
+		{ID: 1, Name: "alice", DisplayName: "Alice"},
+		{ID: 2, Name: "bob", DisplayName: "Bob"},
+		{ID: 3, Name: "carol", DisplayName: "Carol"},
+		{ID: 4, Name: "dave", DisplayName: "Dave"},
+	}
+	f.teams = []Team{
+		{ID: 1, Name: "alpha", LeaderID: 1, Member1ID: 2, Member2ID: nulluserid, Status: teamStatusOfficial},
+		{ID: 2, Name: "beta", LeaderID: 3, Member1ID: nulluserid, Member2ID: nulluserid, Status: teamStatusOfficial},
+	}
+	f.tasks = []Task{{ID: 1, Name: "A", DisplayName: "Task A"}}
+	f.subtasks = []Subtask{
+		{ID: 1, Name: "A1", DisplayName: "A-1", TaskID: 1},
+		{ID: 2, Name: "A2", DisplayName: "A-2", TaskID: 1},
+	}
+	f.answers = []Answer{
+		{ID: 1, TaskID: 1, SubtaskID: 1, Answer: "half", Score: 50},
+		{ID: 2, TaskID: 1, SubtaskID: 1, Answer: "full", Score: 100},
+		{ID: 3, TaskID: 1, SubtaskID: 2, Answer: "two", Score: 50},
+	}
+	return f
+}
+
+func getTaskHistory(t *testing.T, s *Server, userName string) (TaskHistoryResponse, error) {
+	t.Helper()
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/tasks/A/history", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("taskname")
+	c.SetParamValues("A")
+	c.Set(apiTokenUserKey, userName)
+	res := TaskHistoryResponse{}
+	if err := s.getTaskHistoryHandler(c); err != nil {
+		return res, err
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return res, nil
+}
+
+func TestTaskHistoryRunningScore(t *testing.T) {
+	f := newTaskHistoryStore()
+	at := func(minutes int) time.Time { return testEpoch.Add(time.Duration(minutes) * time.Minute) }
+	f.submissions = []Submission{
+		{ID: 1, TaskID: 1, UserID: 1, SubmittedAt: at(1), Answer: "wrong", SubTaskID: -1, Score: 0},
+		{ID: 2, TaskID: 1, UserID: 2, SubmittedAt: at(2), Answer: "half", SubTaskID: 1, Score: 50},
+		{ID: 3, TaskID: 1, UserID: 3, SubmittedAt: at(2), Answer: "full", SubTaskID: 1, Score: 100}, // 別のチーム
+		{ID: 4, TaskID: 1, UserID: 1, SubmittedAt: at(3), Answer: "two", SubTaskID: 2, Score: 50},
+		// 同じ時刻の提出は ID の順に数える
+		{ID: 5, TaskID: 1, UserID: 2, SubmittedAt: at(4), Answer: "half", SubTaskID: 1, Score: 50},
+		{ID: 6, TaskID: 1, UserID: 1, SubmittedAt: at(4), Answer: "full", SubTaskID: 1, Score: 100},
+		{ID: 7, TaskID: 1, UserID: 2, SubmittedAt: at(5), Answer: "half", SubTaskID: 1, Score: 50},
+	}
+	s := newTestServer(f)
+
+	res, err := getTaskHistory(t, s, "alice")
+	if err != nil {
+		t.Fatalf("getTaskHistoryHandler: %v", err)
+	}
+	if res.MaxScore != 150 || res.Score != 150 {
+		t.Errorf("got score %d/%d, want 150/150", res.Score, res.MaxScore)
+	}
+	want := []struct {
+		user      string
+		taskScore int
+		improved  bool
+	}{
+		{"alice", 0, false},
+		{"bob", 50, true},
+		{"alice", 100, true},
+		{"bob", 100, false},
+		{"alice", 150, true},
+		{"bob", 150, false},
+	}
+	if len(res.Submissions) != len(want) {
+		t.Fatalf("got %d submissions, want %d", len(res.Submissions), len(want))
+	}
+	for i, w := range want {
+		got := res.Submissions[i]
+		if got.UserName != w.user || got.TaskScore != w.taskScore || got.Improved != w.improved {
+			t.Errorf("submission %d: got %s %d improved=%v, want %s %d improved=%v", i, got.UserName, got.TaskScore, got.Improved, w.user, w.taskScore, w.improved)
+		}
+	}
+	if got := res.Subtasks[0].SolvedAt; got != at(4).Unix() {
+		t.Errorf("got A1 solved at %d, want %d", got, at(4).Unix())
+	}
+	if got := res.Subtasks[1].SolvedAt; got != at(3).Unix() {
+		t.Errorf("got A2 solved at %d, want %d", got, at(3).Unix())
+	}
+}
+
+func TestTaskHistoryNotInTeam(t *testing.T) {
+	s := newTestServer(newTaskHistoryStore())
+	_, err := getTaskHistory(t, s, "dave")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != ErrCodeNotInTeam {
+		t.Fatalf("got %v, want %s", err, ErrCodeNotInTeam)
+	}
+}
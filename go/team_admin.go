@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/labstack/echo/v4"
+)
+
+var teamStatuses = []string{teamStatusOfficial, teamStatusUnofficial, teamStatusDisqualified, teamStatusHidden}
+
+type AdminUpdateTeamRequest struct {
+	TeamName    string `json:"team_name"`
+	NewName     string `json:"new_name"` // 空なら name は変えない
+	DisplayName string `json:"display_name"`
+	Description string `json:"description"`
+	LeaderName  string `json:"leader_name"`
+	// 空なら空き枠
+	Member1Name string `json:"member1_name"`
+	Member2Name string `json:"member2_name"`
+}
+
+func validateAdminUpdateTeamRequest(req AdminUpdateTeamRequest) ValidationErrors {
+	errs := ValidationErrors{}
+	if req.NewName != "" {
+		validateTeamName(&errs, "new_name", req.NewName)
+	}
+	validateDisplayName(&errs, "display_name", req.DisplayName)
+	validateDescription(&errs, "description", req.Description)
+	validateName(&errs, "leader_name", req.LeaderName)
+	seen := map[string]bool{req.LeaderName: true}
+	for i, name := range []string{req.Member1Name, req.Member2Name} {
+		if name == "" {
+			continue
+		}
+		if seen[name] {
+			errs.add(fmt.Sprintf("member%d_name", i+1), "duplicate member "+name)
+		}
+		seen[name] = true
+	}
+	return errs
+}
+
+// 他のチームに入っていないユーザーの ID。name が空なら nulluserid
+func teamMemberID(ctx context.Context, st Store, teamID int, name string) (int, error) {
+	if name == "" {
+		return nulluserid, nil
+	}
+	usr, err := st.Users().GetByName(ctx, name)
+	if errors.Is(err, ErrNotFound) {
+		return 0, newAPIError(http.StatusBadRequest, ErrCodeUserNotFound, "user "+name+" not found")
+	} else if err != nil {
+		return 0, internalError("failed to get user", err)
+	}
+	team, err := st.Teams().GetByMember(ctx, usr.ID)
+	if err == nil && team.ID != teamID {
+		return 0, newAPIError(http.StatusBadRequest, ErrCodeAlreadyInTeam, name+" is already in team "+team.Name)
+	} else if err != nil && !errors.Is(err, ErrNotFound) {
+		return 0, internalError("failed to get team", err)
+	}
+	return usr.ID, nil
+}
+
+// POST /api/admin/teams/update
+// 表示名・説明・メンバーを書き換える。重複して作られたチームをまとめるときは、先に片方を消してからメンバーを移す
+func (s *Server) updateTeamHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+	defer c.Request().Body.Close()
+
+	if err := verifyAdminSession(c); err != nil {
+		return err
+	}
+	req := AdminUpdateTeamRequest{}
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return newAPIError(http.StatusBadRequest, ErrCodeInvalidRequest, "failed to decode the request body as json")
+	}
+	req.DisplayName = normalizeText(req.DisplayName)
+	req.Description = normalizeText(req.Description)
+	if errs := validateAdminUpdateTeamRequest(req); len(errs) > 0 {
+		return newAPIError(http.StatusBadRequest, ErrCodeValidationFailed, "invalid team").withDetails(errs)
+	}
+
+	teamID := 0
+	err := s.store.WithTx(ctx, func(st Store) error {
+		team, err := st.Teams().GetByName(ctx, req.TeamName)
+		if errors.Is(err, ErrNotFound) {
+			return newAPIError(http.StatusNotFound, ErrCodeTeamNotFound, "team not found")
+		} else if err != nil {
+			return internalError("failed to get team", err)
+		}
+		teamID = team.ID
+		oldMembers := []int{team.LeaderID, team.Member1ID, team.Member2ID}
+
+		if req.NewName != "" && req.NewName != team.Name {
+			_, err := st.Teams().GetByName(ctx, req.NewName)
+			if err == nil {
+				return newAPIError(http.StatusBadRequest, ErrCodeTeamAlreadyExists, "team already exists")
+			} else if !errors.Is(err, ErrNotFound) {
+				return internalError("failed to get team", err)
+			}
+			team.Name = req.NewName
+		}
+		team.DisplayName = req.DisplayName
+		team.Description = req.Description
+
+		if team.LeaderID, err = teamMemberID(ctx, st, team.ID, req.LeaderName); err != nil {
+			return err
+		}
+		// member1 が空で member2 だけ指定されたときは詰める
+		members := []int{}
+		for _, name := range []string{req.Member1Name, req.Member2Name} {
+			id, err := teamMemberID(ctx, st, team.ID, name)
+			if err != nil {
+				return err
+			}
+			if id != nulluserid {
+				members = append(members, id)
+			}
+		}
+		team.Member1ID, team.Member2ID = nulluserid, nulluserid
+		if len(members) > 0 {
+			team.Member1ID = members[0]
+		}
+		if len(members) > 1 {
+			team.Member2ID = members[1]
+		}
+
+		if err := st.Teams().Update(ctx, team); err != nil {
+			return internalError("failed to update team", err)
+		}
+		// first blood は今の所属で数えるので、メンバーを移したら付け替える
+		if !slices.Equal(oldMembers, []int{team.LeaderID, team.Member1ID, team.Member2ID}) {
+			if err := recomputeFirstSolves(ctx, st); err != nil {
+				return internalError("failed to recompute first solves", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return asAPIError("failed to commit transaction", err)
+	}
+	s.cache.Publish(cacheEvent{Kind: cacheEventTeamChanged, TeamID: teamID})
+
+	return c.NoContent(http.StatusOK)
+}
+
+type AdminTeamRequest struct {
+	TeamName string `json:"team_name"`
+}
+
+// POST /api/admin/teams/delete
+// チームだけを消す。提出はユーザーに付いているので、メンバーが別のチームに入ればそちらで数えられる
+func (s *Server) deleteTeamHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+	defer c.Request().Body.Close()
+
+	if err := verifyAdminSession(c); err != nil {
+		return err
+	}
+	req := AdminTeamRequest{}
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return newAPIError(http.StatusBadRequest, ErrCodeInvalidRequest, "failed to decode the request body as json")
+	}
+	team, err := s.store.Teams().GetByName(ctx, req.TeamName)
+	if errors.Is(err, ErrNotFound) {
+		return newAPIError(http.StatusNotFound, ErrCodeTeamNotFound, "team not found")
+	} else if err != nil {
+		return internalError("failed to get team", err)
+	}
+	if err := s.store.Teams().Delete(ctx, team.ID); err != nil {
+		return internalError("failed to delete team", err)
+	}
+	// このチームの first blood は次に解いたチームに移す
+	if err := recomputeFirstSolves(ctx, s.store); err != nil {
+		return internalError("failed to recompute first solves", err)
+	}
+	s.cache.Publish(cacheEvent{Kind: cacheEventTeamChanged, TeamID: team.ID})
+
+	return c.NoContent(http.StatusOK)
+}
+
+type SetTeamStatusRequest struct {
+	TeamName string `json:"team_name"`
+	Status   string `json:"status"`
+}
+
+// POST /api/admin/teams/status
+// official / unofficial (順位を付けない) / disqualified (失格、一番下に出す) / hidden (失格、順位表に出さない)
+func (s *Server) setTeamStatusHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+	defer c.Request().Body.Close()
+
+	if err := verifyAdminSession(c); err != nil {
+		return err
+	}
+	req := SetTeamStatusRequest{}
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return newAPIError(http.StatusBadRequest, ErrCodeInvalidRequest, "failed to decode the request body as json")
+	}
+	if !slices.Contains(teamStatuses, req.Status) {
+		return newAPIError(http.StatusBadRequest, ErrCodeValidationFailed, "invalid status").withDetails(ValidationErrors{
+			{Path: "status", Message: "must be one of official, unofficial, disqualified, hidden"},
+		})
+	}
+	team, err := s.store.Teams().GetByName(ctx, req.TeamName)
+	if errors.Is(err, ErrNotFound) {
+		return newAPIError(http.StatusNotFound, ErrCodeTeamNotFound, "team not found")
+	} else if err != nil {
+		return internalError("failed to get team", err)
+	}
+	eligible := firstSolveEligible(team)
+	team.Status = req.Status
+	if err := s.store.Teams().Update(ctx, team); err != nil {
+		return internalError("failed to update team", err)
+	}
+	if eligible != firstSolveEligible(team) {
+		if err := recomputeFirstSolves(ctx, s.store); err != nil {
+			return internalError("failed to recompute first solves", err)
+		}
+	}
+	s.cache.Publish(cacheEvent{Kind: cacheEventTeamChanged, TeamID: team.ID})
+
+	return c.NoContent(http.StatusOK)
+}
@@ -1,13 +1,14 @@
 package main
 
 import (
-	"database/sql"
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"os/exec"
+	"strconv"
 	"strings"
 
-	"github.com/labstack/echo-contrib/session"
 	"github.com/labstack/echo/v4"
 )
 
@@ -20,8 +21,17 @@ type Team struct {
 	Member2ID      int    `db:"member2_id"`
 	Description    string `db:"description"`
 	InvitationCode string `db:"invitation_code"`
+	Status         string `db:"status"`
 }
 
+// teams.status
+const (
+	teamStatusOfficial     = "official"
+	teamStatusUnofficial   = "unofficial"
+	teamStatusDisqualified = "disqualified"
+	teamStatusHidden       = "hidden"
+)
+
 type CreateTeamRequest struct {
 	Name           string `json:"name"`
 	DisplayName    string `json:"display_name"`
@@ -30,8 +40,8 @@ type CreateTeamRequest struct {
 	InvitationCode string
 }
 
-func generateInvitationCode() string {
-	out, err := exec.Command("/bin/bash", "-c", "openssl rand -hex 8").Output()
+func generateInvitationCode(n int) string {
+	out, err := exec.Command("/bin/bash", "-c", "openssl rand -hex "+strconv.Itoa(n)).Output()
 	if err != nil {
 		return ""
 	}
@@ -39,7 +49,7 @@ func generateInvitationCode() string {
 }
 
 // POST /api/team/create
-func createTeamHandler(c echo.Context) error {
+func (s *Server) createTeamHandler(c echo.Context) error {
 	ctx := c.Request().Context()
 	defer c.Request().Body.Close()
 
@@ -50,53 +60,64 @@ func createTeamHandler(c echo.Context) error {
 	req := CreateTeamRequest{}
 
 	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "failed to decode the request body as json")
+		return newAPIError(http.StatusBadRequest, ErrCodeInvalidRequest, "failed to decode the request body as json")
 	}
 
-	if req.Name == "" || req.DisplayName == "" || req.Description == "" {
-		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	req.DisplayName = normalizeText(req.DisplayName)
+	req.Description = normalizeText(req.Description)
+	errs := ValidationErrors{}
+	validateTeamName(&errs, "name", req.Name)
+	validateDisplayName(&errs, "display_name", req.DisplayName)
+	validateDescription(&errs, "description", req.Description)
+	if len(errs) > 0 {
+		return newAPIError(http.StatusBadRequest, ErrCodeValidationFailed, "invalid team").withDetails(errs)
 	}
 
-	req.InvitationCode = generateInvitationCode()
-
-	sess, _ := session.Get(defaultSessionIDKey, c)
-	username, _ := sess.Values[defaultSessionUserNameKey].(string)
+	req.InvitationCode = generateInvitationCode(s.cfg.Contest.InvitationCodeBytes)
 
-	tx, err := dbConn.BeginTxx(ctx, nil)
-	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to begin transaction: "+err.Error())
-	}
-	defer tx.Rollback()
+	username, _ := loginUserName(c)
 
-	usr := User{}
-	err = tx.GetContext(ctx, &usr, "SELECT * FROM users WHERE name = ?", username)
-	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get user: "+err.Error())
-	}
-	req.leader_id = usr.ID
-
-	team := Team{}
-
-	err = tx.GetContext(ctx, &team, "SELECT * FROM teams WHERE name = ?", req.Name)
-	if err == nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "team already exists")
-	} else if err != sql.ErrNoRows {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get team: "+err.Error())
-	}
+	err := s.store.WithTx(ctx, func(st Store) error {
+		usr, err := st.Users().GetByName(ctx, username)
+		if err != nil {
+			return internalError("failed to get user", err)
+		}
+		req.leader_id = usr.ID
 
-	err = tx.GetContext(ctx, &team, "SELECT * FROM teams WHERE leader_id = ? OR member1_id = ? OR member2_id = ?", usr.ID, usr.ID, usr.ID)
-	if err == nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "you have already joined team")
-	} else if err != sql.ErrNoRows {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get team: "+err.Error())
-	}
+		_, err = st.Teams().GetByName(ctx, req.Name)
+		if err == nil {
+			return newAPIError(http.StatusBadRequest, ErrCodeTeamAlreadyExists, "team already exists")
+		} else if !errors.Is(err, ErrNotFound) {
+			return internalError("failed to get team", err)
+		}
 
-	if _, err = tx.ExecContext(ctx, "INSERT INTO teams (name, display_name, leader_id, member1_id, member2_id, description, invitation_code) VALUES (?, ?, ?, ?, ?, ?, ?)", req.Name, req.DisplayName, req.leader_id, nulluserid, nulluserid, req.Description, req.InvitationCode); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to insert team: "+err.Error())
-	}
+		_, err = st.Teams().GetByMember(ctx, usr.ID)
+		if err == nil {
+			return newAPIError(http.StatusBadRequest, ErrCodeAlreadyInTeam, "you have already joined team")
+		} else if !errors.Is(err, ErrNotFound) {
+			return internalError("failed to get team", err)
+		}
 
-	if err = tx.Commit(); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to commit transaction: "+err.Error())
+		team := Team{
+			Name:           req.Name,
+			DisplayName:    req.DisplayName,
+			LeaderID:       req.leader_id,
+			Member1ID:      nulluserid,
+			Member2ID:      nulluserid,
+			Description:    req.Description,
+			InvitationCode: req.InvitationCode,
+			Status:         teamStatusOfficial,
+		}
+		if err := st.Teams().Create(ctx, team); err != nil {
+			return internalError("failed to insert team", err)
+		}
+		if err := recomputeFirstSolvesForMember(ctx, st, usr.ID); err != nil {
+			return internalError("failed to recompute first solves", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return asAPIError("failed to commit transaction", err)
 	}
 
 	return c.NoContent(http.StatusCreated)
@@ -113,7 +134,7 @@ type JoinTeamResponse struct {
 }
 
 // POST /api/team/join
-func joinTeamHandler(c echo.Context) error {
+func (s *Server) joinTeamHandler(c echo.Context) error {
 	ctx := c.Request().Context()
 	defer c.Request().Body.Close()
 
@@ -124,58 +145,56 @@ func joinTeamHandler(c echo.Context) error {
 	req := JoinTeamRequest{}
 
 	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "failed to decode the request body as json")
+		return newAPIError(http.StatusBadRequest, ErrCodeInvalidRequest, "failed to decode the request body as json")
 	}
 
-	tx, err := dbConn.BeginTxx(ctx, nil)
-	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to begin transaction: "+err.Error())
-	}
-	defer tx.Rollback()
+	username, _ := loginUserName(c)
 
 	team := Team{}
-	err = tx.GetContext(ctx, &team, "SELECT * FROM teams WHERE name = ?", req.TeamName)
-	if err == sql.ErrNoRows {
-		return echo.NewHTTPError(http.StatusBadRequest, "team not found")
-	} else if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get team: "+err.Error())
-	}
-
-	if team.InvitationCode != req.InvitationCode {
-		return echo.NewHTTPError(http.StatusBadRequest, "invalid invitation code")
-	}
+	err := s.store.WithTx(ctx, func(st Store) error {
+		var err error
+		team, err = st.Teams().GetByName(ctx, req.TeamName)
+		if errors.Is(err, ErrNotFound) {
+			return newAPIError(http.StatusBadRequest, ErrCodeTeamNotFound, "team not found")
+		} else if err != nil {
+			return internalError("failed to get team", err)
+		}
 
-	sess, _ := session.Get(defaultSessionIDKey, c)
-	username, _ := sess.Values[defaultSessionUserNameKey].(string)
+		if team.InvitationCode != req.InvitationCode {
+			return newAPIError(http.StatusBadRequest, ErrCodeInvalidInvitationCode, "invalid invitation code")
+		}
 
-	usr := User{}
-	err = tx.GetContext(ctx, &usr, "SELECT * FROM users WHERE name = ?", username)
-	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get user: "+err.Error())
-	}
+		usr, err := st.Users().GetByName(ctx, username)
+		if err != nil {
+			return internalError("failed to get user", err)
+		}
 
-	err = tx.GetContext(ctx, &team, "SELECT * FROM teams WHERE leader_id = ? OR member1_id = ? OR member2_id = ?", usr.ID, usr.ID, usr.ID)
-	if err == nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "you have already joined team")
-	} else if err != sql.ErrNoRows {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get team: "+err.Error())
-	}
+		_, err = st.Teams().GetByMember(ctx, usr.ID)
+		if err == nil {
+			return newAPIError(http.StatusBadRequest, ErrCodeAlreadyInTeam, "you have already joined team")
+		} else if !errors.Is(err, ErrNotFound) {
+			return internalError("failed to get team", err)
+		}
 
-	if team.Member1ID == nulluserid {
-		if _, err := tx.ExecContext(ctx, "UPDATE teams SET member1_id = ? WHERE name = ?", usr.ID, req.TeamName); err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "failed to update team: "+err.Error())
+		if team.Member1ID == nulluserid {
+			team.Member1ID = usr.ID
+		} else if team.Member2ID == nulluserid {
+			team.Member2ID = usr.ID
+		} else {
+			return newAPIError(http.StatusBadRequest, ErrCodeTeamFull, "team is full")
 		}
-	} else if team.Member2ID == nulluserid {
-		if _, err := tx.ExecContext(ctx, "UPDATE teams SET member2_id = ? WHERE name = ?", usr.ID, req.TeamName); err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "failed to update team: "+err.Error())
+		if err := st.Teams().UpdateMembers(ctx, team); err != nil {
+			return internalError("failed to update team", err)
 		}
-	} else {
-		return echo.NewHTTPError(http.StatusBadRequest, "team is full")
-	}
-
-	if err = tx.Commit(); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to commit transaction: "+err.Error())
+		if err := recomputeFirstSolvesForMember(ctx, st, usr.ID); err != nil {
+			return internalError("failed to recompute first solves", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return asAPIError("failed to commit transaction", err)
 	}
+	s.cache.Publish(cacheEvent{Kind: cacheEventTeamChanged, TeamID: team.ID})
 
 	return c.JSON(http.StatusCreated, JoinTeamResponse{
 		TeamName:        team.Name,
@@ -195,105 +214,98 @@ type TeamResponse struct {
 	Description        string `json:"description"`
 	SubmissionCount    int    `json:"submission_count"`
 	InvitationCode     string `json:"invitation_code,omitempty"`
+	Status             string `json:"status"`
 }
 
-// GET /api/team/:teamname
-func getTeamHandler(c echo.Context) error {
-	teamname := c.Param("teamname")
-
-	tx, err := dbConn.BeginTxx(c.Request().Context(), nil)
+// キャッシュを見てからユーザーを取得する
+func (s *Server) getUserByID(ctx context.Context, id int) (User, error) {
+	if u, ok := s.cache.Users.Get(id); ok {
+		return u, nil
+	}
+	user, err := s.store.Users().GetByID(ctx, id)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to begin transaction: "+err.Error())
+		return User{}, err
 	}
-	defer tx.Rollback()
+	s.cache.Users.Set(id, user)
+	return user, nil
+}
 
-	team := Team{}
+func (s *Server) getUserByName(ctx context.Context, name string) (User, error) {
+	if u, ok := s.cache.UsersByName.Get(name); ok {
+		return u, nil
+	}
+	user, err := s.store.Users().GetByName(ctx, name)
+	if err != nil {
+		return User{}, err
+	}
+	s.cache.UsersByName.Set(name, user)
+	return user, nil
+}
 
-	err = tx.GetContext(c.Request().Context(), &team, "SELECT * FROM teams WHERE name = ?", teamname)
+// GET /api/team/:teamname
+func (s *Server) getTeamHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+	teamname := c.Param("teamname")
 
-	if err == sql.ErrNoRows {
-		return echo.NewHTTPError(http.StatusNotFound, "team not found")
+	team, err := s.store.Teams().GetByName(ctx, teamname)
+	if errors.Is(err, ErrNotFound) {
+		return newAPIError(http.StatusNotFound, ErrCodeTeamNotFound, "team not found")
 	} else if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get team: "+err.Error())
+		return internalError("failed to get team", err)
 	}
 
 	res := TeamResponse{
 		Name:        team.Name,
 		DisplayName: team.DisplayName,
 		Description: team.Description,
+		Status:      team.Status,
 	}
 
-	leader := User{}
-	if u, ok := usercache.Load(team.LeaderID); ok {
-		leader = u.(User)
-	} else {
-		err = tx.GetContext(c.Request().Context(), &leader, "SELECT * FROM users WHERE id = ?", team.LeaderID)
-		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "failed to get leader: "+err.Error())
-		}
-		usercache.Store(team.LeaderID, leader)
+	leader, err := s.getUserByID(ctx, team.LeaderID)
+	if err != nil {
+		return internalError("failed to get leader", err)
 	}
 	res.LeaderName = leader.Name
 	res.LeaderDisplayName = leader.DisplayName
 
-	if err = tx.GetContext(c.Request().Context(), &res.SubmissionCount, "SELECT COUNT(*) FROM submissions WHERE user_id = ?", leader.ID); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get submission count: "+err.Error())
+	if res.SubmissionCount, err = s.store.Submissions().CountByUser(ctx, leader.ID); err != nil {
+		return internalError("failed to get submission count", err)
 	}
 
 	if team.Member1ID != nulluserid {
-		member1 := User{}
-		if u, ok := usercache.Load(team.Member1ID); ok {
-			member1 = u.(User)
-		} else {
-			err = tx.GetContext(c.Request().Context(), &member1, "SELECT * FROM users WHERE id = ?", team.Member1ID)
-			if err != nil {
-				return echo.NewHTTPError(http.StatusInternalServerError, "failed to get member1: "+err.Error())
-			}
-			usercache.Store(team.Member1ID, member1)
+		member1, err := s.getUserByID(ctx, team.Member1ID)
+		if err != nil {
+			return internalError("failed to get member1", err)
 		}
 		res.Member1Name = member1.Name
 		res.Member1DisplayName = member1.DisplayName
 
-		membersubmissioncount := 0
-		if err = tx.GetContext(c.Request().Context(), &membersubmissioncount, "SELECT COUNT(*) FROM submissions WHERE user_id = ?", member1.ID); err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "failed to get submission count: "+err.Error())
+		membersubmissioncount, err := s.store.Submissions().CountByUser(ctx, member1.ID)
+		if err != nil {
+			return internalError("failed to get submission count", err)
 		}
 		res.SubmissionCount += membersubmissioncount
 	}
 
 	if team.Member2ID != nulluserid {
-		member2 := User{}
-		if u, ok := usercache.Load(team.Member2ID); ok {
-			member2 = u.(User)
-		} else {
-			err = tx.GetContext(c.Request().Context(), &member2, "SELECT * FROM users WHERE id = ?", team.Member2ID)
-			if err != nil {
-				return echo.NewHTTPError(http.StatusInternalServerError, "failed to get member2: "+err.Error())
-			}
-			usercache.Store(team.Member2ID, member2)
+		member2, err := s.getUserByID(ctx, team.Member2ID)
+		if err != nil {
+			return internalError("failed to get member2", err)
 		}
 		res.Member2Name = member2.Name
 		res.Member2DisplayName = member2.DisplayName
 
-		membersubmissioncount := 0
-		if err = tx.GetContext(c.Request().Context(), &membersubmissioncount, "SELECT COUNT(*) FROM submissions WHERE user_id = ?", member2.ID); err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "failed to get submission count: "+err.Error())
+		membersubmissioncount, err := s.store.Submissions().CountByUser(ctx, member2.ID)
+		if err != nil {
+			return internalError("failed to get submission count", err)
 		}
 		res.SubmissionCount += membersubmissioncount
 	}
 
-	sess, err := session.Get(defaultSessionIDKey, c)
-	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get session: "+err.Error())
-	}
-	username, ok := sess.Values[defaultSessionUserNameKey].(string)
-	if ok && username == res.LeaderName {
+	// 招待コードはリーダーにだけ見せる
+	if username, err := loginUserName(c); err == nil && username == res.LeaderName {
 		res.InvitationCode = team.InvitationCode
 	}
 
-	if err = tx.Commit(); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to commit transaction: "+err.Error())
-	}
-
 	return c.JSON(http.StatusOK, res)
 }
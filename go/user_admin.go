@@ -0,0 +1,400 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo-contrib/session"
+	"github.com/labstack/echo/v4"
+)
+
+// 管理者が発行したパスワード再設定用のトークン。トークンそのものは保存せず SHA-256 だけを持つ
+type PasswordReset struct {
+	UserID    int       `db:"user_id"`
+	TokenHash string    `db:"token_hash"`
+	CreatedAt time.Time `db:"created_at"`
+	ExpiresAt time.Time `db:"expires_at"`
+}
+
+type AdminUserResponse struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	Description string `json:"description"`
+	Disabled    bool   `json:"disabled"`
+	TeamName    string `json:"team_name,omitempty"`
+}
+
+type AdminUsersResponse struct {
+	Users     []AdminUserResponse `json:"users"`
+	UserCount int                 `json:"user_count"`
+}
+
+// GET /api/admin/users
+// ?q= で name か display_name の部分一致、?page= でページ (1 から)
+func (s *Server) getUsersHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+	if err := verifyAdminSession(c); err != nil {
+		return err
+	}
+
+	page := 1
+	if c.QueryParam("page") != "" {
+		p, err := strconv.Atoi(c.QueryParam("page"))
+		if err != nil {
+			return newAPIError(http.StatusBadRequest, ErrCodeInvalidPage, "failed to parse page")
+		}
+		page = p
+	}
+	if page < 1 {
+		return newAPIError(http.StatusBadRequest, ErrCodeInvalidPage, "page must be positive")
+	}
+	perPage := s.cfg.Pagination.UsersPerPage
+	filter := UserFilter{Query: c.QueryParam("q"), Offset: (page - 1) * perPage, Limit: perPage}
+
+	count, err := s.store.Users().Count(ctx, filter)
+	if err != nil {
+		return internalError("failed to count users", err)
+	}
+	users, err := s.store.Users().List(ctx, filter)
+	if err != nil {
+		return internalError("failed to get users", err)
+	}
+	res := AdminUsersResponse{Users: make([]AdminUserResponse, 0, len(users)), UserCount: count}
+	for _, u := range users {
+		user := AdminUserResponse{
+			ID:          u.ID,
+			Name:        u.Name,
+			DisplayName: u.DisplayName,
+			Description: u.Description,
+			Disabled:    u.Disabled,
+		}
+		team, err := s.store.Teams().GetByMember(ctx, u.ID)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return internalError("failed to get team", err)
+		} else if err == nil {
+			user.TeamName = team.Name
+		}
+		res.Users = append(res.Users, user)
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+type AdminUserRequest struct {
+	UserName string `json:"user_name"`
+}
+
+// POST /api/admin/users/disable
+// ログインと提出をできなくし、今のセッションもすべてログアウトさせる。API トークンも消す
+func (s *Server) disableUserHandler(c echo.Context) error {
+	return s.setUserDisabled(c, true)
+}
+
+// POST /api/admin/users/enable
+func (s *Server) enableUserHandler(c echo.Context) error {
+	return s.setUserDisabled(c, false)
+}
+
+func (s *Server) setUserDisabled(c echo.Context, disabled bool) error {
+	ctx := c.Request().Context()
+	defer c.Request().Body.Close()
+
+	if err := verifyAdminSession(c); err != nil {
+		return err
+	}
+	req := AdminUserRequest{}
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return newAPIError(http.StatusBadRequest, ErrCodeInvalidRequest, "failed to decode the request body as json")
+	}
+	if req.UserName == "admin" {
+		return newAPIError(http.StatusBadRequest, ErrCodeInvalidRequest, "admin cannot be disabled")
+	}
+	usr, err := s.store.Users().GetByName(ctx, req.UserName)
+	if errors.Is(err, ErrNotFound) {
+		return newAPIError(http.StatusNotFound, ErrCodeUserNotFound, "user not found")
+	} else if err != nil {
+		return internalError("failed to get user", err)
+	}
+	usr.Disabled = disabled
+	if err := s.store.Users().Update(ctx, usr); err != nil {
+		return internalError("failed to update user", err)
+	}
+	s.cache.Publish(cacheEvent{Kind: cacheEventUserChanged, UserID: usr.ID})
+	if disabled {
+		if err := s.store.APITokens().DeleteByUser(ctx, usr.Name); err != nil {
+			return internalError("failed to revoke api tokens", err)
+		}
+		if err := s.sessions.backend.DeleteByUser(ctx, usr.Name); err != nil {
+			return internalError("failed to revoke sessions", err)
+		}
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// 無効にしたユーザー (と、削除して名前が変わったユーザー) のセッションはログインしていないものとして扱う
+// 無効にしたときにセッションは消しているが、消す前に読み込まれていたものや他のプロセスのメモリに残っているものも弾く
+// すべてのリクエストで通るので、ユーザーはキャッシュから読む (変更したときに cacheEventUserChanged で消える)
+// API トークンの持ち主は apiTokenAuth で確かめる
+func (s *Server) rejectDisabledSessions() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if _, ok := c.Get(apiTokenUserKey).(string); ok {
+				return next(c)
+			}
+			sess, err := session.Get(defaultSessionIDKey, c)
+			if err != nil {
+				return internalError("failed to get session", err)
+			}
+			username, _ := sess.Values[defaultSessionUserNameKey].(string)
+			if username == "" {
+				return next(c)
+			}
+			usr, err := s.getUserByName(c.Request().Context(), username)
+			if err != nil && !errors.Is(err, ErrNotFound) {
+				return internalError("failed to get user", err)
+			}
+			if err != nil || usr.Disabled {
+				delete(sess.Values, defaultSessionUserNameKey)
+			}
+			return next(c)
+		}
+	}
+}
+
+type PasswordResetTokenResponse struct {
+	Token     string `json:"token"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// POST /api/admin/users/reset-password
+// 今のパスワードを使えなくして、再設定用のトークンを返す。管理者がこれをユーザーに渡す
+// セッションと API トークンも無効にする
+func (s *Server) resetUserPasswordHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+	defer c.Request().Body.Close()
+
+	if err := verifyAdminSession(c); err != nil {
+		return err
+	}
+	req := AdminUserRequest{}
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return newAPIError(http.StatusBadRequest, ErrCodeInvalidRequest, "failed to decode the request body as json")
+	}
+	if req.UserName == "admin" {
+		return newAPIError(http.StatusBadRequest, ErrCodeInvalidRequest, "admin password cannot be reset")
+	}
+	token, err := randomToken()
+	if err != nil {
+		return internalError("failed to generate reset token", err)
+	}
+	now := dbNow()
+	reset := PasswordReset{
+		TokenHash: hashSessionToken(token),
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.cfg.Login.PasswordResetTTL),
+	}
+
+	err = s.store.WithTx(ctx, func(st Store) error {
+		usr, err := st.Users().GetByName(ctx, req.UserName)
+		if errors.Is(err, ErrNotFound) {
+			return newAPIError(http.StatusNotFound, ErrCodeUserNotFound, "user not found")
+		} else if err != nil {
+			return internalError("failed to get user", err)
+		}
+		usr.Passhash = unusablePasshash
+		if err := st.Users().Update(ctx, usr); err != nil {
+			return internalError("failed to update user", err)
+		}
+		reset.UserID = usr.ID
+		if err := st.Users().SavePasswordReset(ctx, reset); err != nil {
+			return internalError("failed to save password reset", err)
+		}
+		// 漏れたトークンで使い続けられないように、パスワードと一緒に無効にする
+		if err := st.APITokens().DeleteByUser(ctx, usr.Name); err != nil {
+			return internalError("failed to revoke api tokens", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return asAPIError("failed to commit transaction", err)
+	}
+	s.cache.Publish(cacheEvent{Kind: cacheEventUserChanged, UserID: reset.UserID})
+	if err := s.sessions.backend.DeleteByUser(ctx, req.UserName); err != nil {
+		return internalError("failed to revoke sessions", err)
+	}
+
+	return c.JSON(http.StatusOK, PasswordResetTokenResponse{Token: token, ExpiresAt: reset.ExpiresAt.Unix()})
+}
+
+type PasswordResetRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// POST /api/password-reset
+// 管理者から受け取ったトークンで新しいパスワードを設定する。トークンは一度しか使えない
+// パスワードを変えたときと同じく API トークンも無効にする
+func (s *Server) passwordResetHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+	defer c.Request().Body.Close()
+
+	req := PasswordResetRequest{}
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return newAPIError(http.StatusBadRequest, ErrCodeInvalidRequest, "failed to decode the request body as json")
+	}
+	if req.NewPassword == "" {
+		return newAPIError(http.StatusBadRequest, ErrCodeValidationFailed, "invalid password").withDetails(ValidationErrors{
+			{Path: "new_password", Message: "must not be empty"},
+		})
+	}
+
+	userName, userID := "", 0
+	err := s.store.WithTx(ctx, func(st Store) error {
+		reset, err := st.Users().GetPasswordReset(ctx, hashSessionToken(req.Token))
+		if errors.Is(err, ErrNotFound) {
+			return newAPIError(http.StatusBadRequest, ErrCodeInvalidResetToken, "invalid or expired reset token")
+		} else if err != nil {
+			return internalError("failed to get password reset", err)
+		}
+		if !reset.ExpiresAt.After(dbNow()) {
+			return newAPIError(http.StatusBadRequest, ErrCodeInvalidResetToken, "invalid or expired reset token")
+		}
+		usr, err := st.Users().GetByID(ctx, reset.UserID)
+		if err != nil {
+			return internalError("failed to get user", err)
+		}
+		usr.Passhash = calcsha256(req.NewPassword)
+		if err := st.Users().Update(ctx, usr); err != nil {
+			return internalError("failed to update user", err)
+		}
+		if err := st.Users().DeletePasswordReset(ctx, usr.ID); err != nil {
+			return internalError("failed to delete password reset", err)
+		}
+		if err := st.APITokens().DeleteByUser(ctx, usr.Name); err != nil {
+			return internalError("failed to revoke api tokens", err)
+		}
+		userName, userID = usr.Name, usr.ID
+		return nil
+	})
+	if err != nil {
+		return asAPIError("failed to commit transaction", err)
+	}
+	s.cache.Publish(cacheEvent{Kind: cacheEventUserChanged, UserID: userID})
+	// 再設定の前に間違えた分でロックされたままにならないようにする
+	if err := s.clearLockout(ctx, userName); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+// 1 回のリクエストで作れるユーザーの数
+const maxProvisionUsers = 1000
+
+type ProvisionUser struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	Description string `json:"description"`
+}
+
+type ProvisionUsersRequest struct {
+	Users []ProvisionUser `json:"users"`
+}
+
+func validateProvisionUsersRequest(req ProvisionUsersRequest) ValidationErrors {
+	errs := ValidationErrors{}
+	if len(req.Users) == 0 {
+		errs.add("users", "must not be empty")
+	} else if len(req.Users) > maxProvisionUsers {
+		errs.add("users", fmt.Sprintf("must be at most %d users", maxProvisionUsers))
+	}
+	seen := map[string]bool{}
+	for i, u := range req.Users {
+		path := fmt.Sprintf("users[%d]", i)
+		validateUserName(&errs, path+".name", u.Name)
+		validateDisplayName(&errs, path+".display_name", u.DisplayName)
+		if u.Description != "" {
+			validateDescription(&errs, path+".description", u.Description)
+		}
+		if seen[u.Name] {
+			errs.add(path+".name", "duplicate name "+u.Name)
+		}
+		seen[u.Name] = true
+	}
+	return errs
+}
+
+// POST /api/admin/users/provision
+// 登録の設定に関係なくまとめてユーザーを作り、name,display_name,password の CSV を返す
+// パスワードはここでしか分からないので、管理者が参加者に配る
+func (s *Server) provisionUsersHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+	defer c.Request().Body.Close()
+
+	if err := verifyAdminSession(c); err != nil {
+		return err
+	}
+	req := ProvisionUsersRequest{}
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return newAPIError(http.StatusBadRequest, ErrCodeInvalidRequest, "failed to decode the request body as json")
+	}
+	for i := range req.Users {
+		req.Users[i].DisplayName = normalizeText(req.Users[i].DisplayName)
+		req.Users[i].Description = normalizeText(req.Users[i].Description)
+	}
+	if errs := validateProvisionUsersRequest(req); len(errs) > 0 {
+		return newAPIError(http.StatusBadRequest, ErrCodeValidationFailed, "invalid users").withDetails(errs)
+	}
+
+	passwords := make([]string, len(req.Users))
+	for i := range req.Users {
+		token, err := randomToken()
+		if err != nil {
+			return internalError("failed to generate password", err)
+		}
+		passwords[i] = token[:16]
+	}
+
+	err := s.store.WithTx(ctx, func(st Store) error {
+		errs := ValidationErrors{}
+		for i, u := range req.Users {
+			_, err := st.Users().GetByName(ctx, u.Name)
+			if err == nil {
+				errs.add(fmt.Sprintf("users[%d].name", i), "user already exists")
+			} else if !errors.Is(err, ErrNotFound) {
+				return internalError("failed to get user", err)
+			}
+		}
+		if len(errs) > 0 {
+			return newAPIError(http.StatusBadRequest, ErrCodeUserAlreadyExists, "some users already exist").withDetails(errs)
+		}
+		for i, u := range req.Users {
+			if err := st.Users().Create(ctx, User{Name: u.Name, DisplayName: u.DisplayName, Description: u.Description, Passhash: calcsha256(passwords[i])}); err != nil {
+				return internalError("failed to insert user", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return asAPIError("failed to commit transaction", err)
+	}
+
+	buf := &bytes.Buffer{}
+	w := csv.NewWriter(buf)
+	w.Write([]string{"name", "display_name", "password"})
+	for i, u := range req.Users {
+		w.Write([]string{u.Name, u.DisplayName, passwords[i]})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return internalError("failed to write csv", err)
+	}
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="users.csv"`)
+	return c.Blob(http.StatusCreated, "text/csv; charset=utf-8", buf.Bytes())
+}
@@ -1,13 +1,13 @@
 package main
 
 import (
-	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"os/exec"
 	"strings"
 
-	"github.com/gorilla/sessions"
 	"github.com/labstack/echo-contrib/session"
 	"github.com/labstack/echo/v4"
 )
@@ -24,6 +24,7 @@ type User struct {
 	DisplayName string `db:"display_name"`
 	Description string `db:"description"`
 	Passhash    string `db:"passhash"`
+	Disabled    bool   `db:"disabled"`
 }
 
 type RegisterRequest struct {
@@ -31,15 +32,38 @@ type RegisterRequest struct {
 	DisplayName string `json:"display_name"`
 	Description string `json:"description"`
 	Password    string `json:"password"` // ハッシュ化されていない
+	// registration.mode が code のときに必要
+	RegistrationCode string `json:"registration_code"`
 }
 
-func verifyUserSession(c echo.Context) error {
+// ログインしているユーザーの名前。API トークンで来たリクエストならトークンの持ち主
+func loginUserName(c echo.Context) (string, error) {
+	if username, ok := c.Get(apiTokenUserKey).(string); ok {
+		return username, nil
+	}
 	sess, err := session.Get(defaultSessionIDKey, c)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get session")
+		return "", internalError("failed to get session", err)
 	}
-	if sess.Values[defaultSessionUserNameKey] == nil {
-		return echo.NewHTTPError(http.StatusUnauthorized, "not logged in")
+	username, _ := sess.Values[defaultSessionUserNameKey].(string)
+	if username == "" {
+		return "", newAPIError(http.StatusUnauthorized, ErrCodeNotLoggedIn, "not logged in")
+	}
+	return username, nil
+}
+
+func verifyUserSession(c echo.Context) error {
+	_, err := loginUserName(c)
+	return err
+}
+
+func verifyAdminSession(c echo.Context) error {
+	username, err := loginUserName(c)
+	if err != nil {
+		return err
+	}
+	if username != "admin" {
+		return newAPIError(http.StatusUnauthorized, ErrCodeNotAdmin, "not admin")
 	}
 	return nil
 }
@@ -56,45 +80,51 @@ func calcsha256(s string) string {
 }
 
 // POST /api/register
-func registerHandler(c echo.Context) error {
+func (s *Server) registerHandler(c echo.Context) error {
 	ctx := c.Request().Context()
 	defer c.Request().Body.Close()
 
 	req := RegisterRequest{}
 
 	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "failed to decode the request body as json")
+		return newAPIError(http.StatusBadRequest, ErrCodeInvalidRequest, "failed to decode the request body as json")
 	}
 
-	if req.Name == "" || req.DisplayName == "" || req.Description == "" || req.Password == "" {
-		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	req.DisplayName = normalizeText(req.DisplayName)
+	req.Description = normalizeText(req.Description)
+	errs := ValidationErrors{}
+	validateUserName(&errs, "name", req.Name)
+	validateDisplayName(&errs, "display_name", req.DisplayName)
+	validateDescription(&errs, "description", req.Description)
+	if req.Password == "" {
+		errs.add("password", "must not be empty")
 	}
-
-	tx, err := dbConn.BeginTxx(ctx, nil)
-	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to begin transaction: "+err.Error())
-	}
-	defer tx.Rollback()
-
-	// 同じ name のユーザーがいないか確認
-	usr := User{}
-
-	err = tx.GetContext(ctx, &usr, "SELECT * FROM users WHERE name = ?", req.Name)
-
-	if err == nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "user already exists")
-	} else if err != sql.ErrNoRows {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get user: "+err.Error())
+	if len(errs) > 0 {
+		return newAPIError(http.StatusBadRequest, ErrCodeValidationFailed, "invalid user").withDetails(errs)
 	}
 
-	pashhash := calcsha256(req.Password)
-
-	if _, err = tx.ExecContext(ctx, "INSERT INTO users (name, display_name, description, passhash) VALUES (?, ?, ?, ?)", req.Name, req.DisplayName, req.Description, pashhash); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to insert user: "+err.Error())
-	}
-
-	if err = tx.Commit(); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to commit transaction: "+err.Error())
+	err := s.store.WithTx(ctx, func(st Store) error {
+		if err := s.checkRegistration(ctx, st, req); err != nil {
+			return err
+		}
+
+		// 同じ name のユーザーがいないか確認
+		_, err := st.Users().GetByName(ctx, req.Name)
+		if err == nil {
+			return newAPIError(http.StatusBadRequest, ErrCodeUserAlreadyExists, "user already exists")
+		} else if !errors.Is(err, ErrNotFound) {
+			return internalError("failed to get user", err)
+		}
+
+		pashhash := calcsha256(req.Password)
+
+		if err := st.Users().Create(ctx, User{Name: req.Name, DisplayName: req.DisplayName, Description: req.Description, Passhash: pashhash}); err != nil {
+			return internalError("failed to insert user", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return asAPIError("failed to commit transaction", err)
 	}
 
 	return c.NoContent(http.StatusCreated)
@@ -110,94 +140,116 @@ type LoginResponse struct {
 	DisplayName     string `json:"display_name"`
 	TeamName        string `json:"team_name,omitempty"`
 	TeamDisplayName string `json:"team_display_name,omitempty"`
+	// GET /api/csrf-token と同じもの
+	CSRFToken string `json:"csrf_token"`
 }
 
 // POST /api/login
-func loginHandler(c echo.Context) error {
+func (s *Server) loginHandler(c echo.Context) error {
 	ctx := c.Request().Context()
 	defer c.Request().Body.Close()
 
 	req := LoginRequest{}
 
 	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "failed to decode the request body as json")
+		return newAPIError(http.StatusBadRequest, ErrCodeInvalidRequest, "failed to decode the request body as json")
 	}
-
-	tx, err := dbConn.BeginTxx(ctx, nil)
-	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to begin transaction: "+err.Error())
+	// この長さのユーザーはいないので、ロックや試行の記録を DB に書く前に弾く
+	if len(req.Name) > maxNameLength {
+		return newAPIError(http.StatusBadRequest, ErrCodeValidationFailed, "invalid login").withDetails(ValidationErrors{
+			{Path: "name", Message: fmt.Sprintf("must be at most %d bytes", maxNameLength)},
+		})
 	}
-	defer tx.Rollback()
 
-	usr := User{}
-
-	err = tx.GetContext(ctx, &usr, "SELECT * FROM users WHERE name = ?", req.Name)
+	if err := s.checkLockout(c, req.Name); err != nil {
+		return err
+	}
 
-	if err == sql.ErrNoRows {
-		return echo.NewHTTPError(http.StatusUnauthorized, "user not found")
+	usr, err := s.store.Users().GetByName(ctx, req.Name)
+	if errors.Is(err, ErrNotFound) {
+		if err := s.recordLoginAttempt(c, req.Name, false, loginReasonUnknownUser); err != nil {
+			return err
+		}
+		return newAPIError(http.StatusUnauthorized, ErrCodeUserNotFound, "user not found")
 	} else if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get user: "+err.Error())
+		return internalError("failed to get user", err)
 	}
 
 	pashhash := calcsha256(req.Password)
 
 	if usr.Passhash != pashhash {
-		return echo.NewHTTPError(http.StatusUnauthorized, "authentication failed")
+		if err := s.recordLoginFailure(c, usr.Name); err != nil {
+			return err
+		}
+		return newAPIError(http.StatusUnauthorized, ErrCodeAuthenticationFailed, "authentication failed")
+	}
+	if usr.Disabled {
+		if err := s.recordLoginAttempt(c, usr.Name, false, loginReasonDisabled); err != nil {
+			return err
+		}
+		return newAPIError(http.StatusForbidden, ErrCodeAccountDisabled, "account is disabled")
+	}
+	if err := s.recordLoginAttempt(c, usr.Name, true, loginReasonOK); err != nil {
+		return err
 	}
 
-	team := Team{}
 	teamfound := false
-	err = tx.GetContext(ctx, &team, "SELECT teams.* FROM teams JOIN users ON leader_id = users.id OR member1_id = users.id OR member2_id = users.id WHERE users.name = ?", req.Name)
-	if err != nil && err != sql.ErrNoRows {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get team info: "+err.Error())
+	team, err := s.store.Teams().GetByMember(ctx, usr.ID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return internalError("failed to get team info", err)
 	} else if err == nil {
 		teamfound = true
+		setLogTeam(c, team.Name)
 	}
 
 	sess, err := session.Get(defaultSessionIDKey, c)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get session: "+err.Error())
+		return internalError("failed to get session", err)
 	}
-	sess.Options = &sessions.Options{
-		MaxAge:   86400 * 7,
-		HttpOnly: true,
+	if err := s.sessions.renew(ctx, sess); err != nil {
+		return internalError("failed to renew session", err)
+	}
+	opts := sessionOptions(s.cfg.Session, s.cfg.Session.MaxAge)
+	sess.Options = &opts
+	csrfToken, err := randomToken()
+	if err != nil {
+		return internalError("failed to generate csrf token", err)
 	}
 	sess.Values[defaultSessionUserNameKey] = usr.Name
+	sess.Values[defaultSessionCSRFTokenKey] = csrfToken
 	if err = sess.Save(c.Request(), c.Response()); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to save session: "+err.Error())
+		return internalError("failed to save session", err)
 	}
 
-	if err := tx.Commit(); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to commit transaction: "+err.Error())
-	}
 	if teamfound {
 		return c.JSON(http.StatusOK, LoginResponse{
 			Name:            usr.Name,
 			DisplayName:     usr.DisplayName,
 			TeamName:        team.Name,
 			TeamDisplayName: team.DisplayName,
+			CSRFToken:       csrfToken,
 		})
 	} else {
 		return c.JSON(http.StatusOK, LoginResponse{
 			Name:        usr.Name,
 			DisplayName: usr.DisplayName,
+			CSRFToken:   csrfToken,
 		})
 	}
 }
 
 // POST /api/logout
-func logoutHandler(c echo.Context) error {
+func (s *Server) logoutHandler(c echo.Context) error {
 	sess, err := session.Get(defaultSessionIDKey, c)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get session: "+err.Error())
-	}
-	sess.Options = &sessions.Options{
-		MaxAge:   -1,
-		HttpOnly: true,
+		return internalError("failed to get session", err)
 	}
+	// サーバー側のセッションも消えるので、同じクッキーはもう使えない
+	opts := sessionOptions(s.cfg.Session, -1)
+	sess.Options = &opts
 	sess.Values[defaultSessionUserNameKey] = ""
 	if err = sess.Save(c.Request(), c.Response()); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to save session: "+err.Error())
+		return internalError("failed to save session", err)
 	}
 
 	return c.NoContent(http.StatusOK)
@@ -213,23 +265,15 @@ type UserResponse struct {
 }
 
 // GET /api/user/:username
-func getUserHandler(c echo.Context) error {
+func (s *Server) getUserHandler(c echo.Context) error {
+	ctx := c.Request().Context()
 	username := c.Param("username")
 
-	tx, err := dbConn.BeginTxx(c.Request().Context(), nil)
-	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to begin transaction: "+err.Error())
-	}
-	defer tx.Rollback()
-
-	usr := User{}
-
-	err = tx.GetContext(c.Request().Context(), &usr, "SELECT * FROM users WHERE name = ?", username)
-
-	if err == sql.ErrNoRows {
-		return echo.NewHTTPError(http.StatusNotFound, "user not found")
+	usr, err := s.store.Users().GetByName(ctx, username)
+	if errors.Is(err, ErrNotFound) {
+		return newAPIError(http.StatusNotFound, ErrCodeUserNotFound, "user not found")
 	} else if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get user: "+err.Error())
+		return internalError("failed to get user", err)
 	}
 
 	res := UserResponse{
@@ -238,24 +282,18 @@ func getUserHandler(c echo.Context) error {
 		Description: usr.Description,
 	}
 
-	err = tx.GetContext(c.Request().Context(), &res.SubmissionCount, "SELECT COUNT(*) FROM submissions JOIN users ON user_id = users.id WHERE name = ?", username)
+	res.SubmissionCount, err = s.store.Submissions().CountByUser(ctx, usr.ID)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get submission count: "+err.Error())
+		return internalError("failed to get submission count", err)
 	}
 
-	team := Team{}
-
-	err = tx.GetContext(c.Request().Context(), &team, "SELECT teams.* FROM teams JOIN users ON leader_id = users.id OR member1_id = users.id OR member2_id = users.id WHERE users.name = ?", username)
-	if err != nil && err != sql.ErrNoRows {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get team info: "+err.Error())
+	team, err := s.store.Teams().GetByMember(ctx, usr.ID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return internalError("failed to get team info", err)
 	} else if err == nil {
 		res.TeamName = team.Name
 		res.TeamDisplayName = team.DisplayName
 	}
 
-	if err = tx.Commit(); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to commit transaction: "+err.Error())
-	}
-
 	return c.JSON(http.StatusOK, res)
 }
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// VARCHAR(255) のカラムに入る長さ
+	maxNameLength   = 255
+	maxAnswerLength = 255
+	// login_attempts と sessions に記録するもの (長すぎたら切り詰める)
+	maxIPLength        = 64
+	maxUserAgentLength = 512
+
+	// ユーザー名・チーム名は URL (/api/user/:username など) にそのまま入る
+	maxUserNameLength    = 32
+	maxTeamNameLength    = 64
+	maxDisplayNameLength = 64 // 文字数
+	maxDescriptionLength = 2000
+)
+
+// ユーザー名・チーム名に使える文字。削除したユーザーの ~deleted-<id> とは被らない
+var identifierPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// 管理画面や URL と紛らわしいユーザー名・チーム名。大文字小文字は区別しない
+// 既にこの名前で作られているユーザー (初期データの admin など) はそのまま使える
+var reservedNames = []string{"admin", "administrator", "root", "system", "api", "me", "null", "undefined"}
+
+type FieldError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+type ValidationErrors []FieldError
+
+func (v ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(v))
+	for _, fe := range v {
+		msgs = append(msgs, fe.Path+": "+fe.Message)
+	}
+	return strings.Join(msgs, ", ")
+}
+
+func (v *ValidationErrors) add(path string, message string) {
+	*v = append(*v, FieldError{Path: path, Message: message})
+}
+
+// VARCHAR(n) に入るように先頭 n 文字に切り詰める。MySQL は strict モードなので、長すぎると INSERT が失敗する
+func truncateString(s string, n int) string {
+	count := 0
+	for i := range s {
+		if count == n {
+			return s[:i]
+		}
+		count++
+	}
+	return s
+}
+
+func validateName(errs *ValidationErrors, path string, name string) {
+	if name == "" {
+		errs.add(path, "must not be empty")
+	} else if len(name) > maxNameLength {
+		errs.add(path, fmt.Sprintf("must be at most %d bytes", maxNameLength))
+	}
+}
+
+// URL に入る名前の文字と長さだけを見る。エラーを追加しなければ true
+func validateIdentifier(errs *ValidationErrors, path string, name string, maxLength int) bool {
+	switch {
+	case name == "":
+		errs.add(path, "must not be empty")
+	case len(name) > maxLength:
+		errs.add(path, fmt.Sprintf("must be at most %d characters", maxLength))
+	case !identifierPattern.MatchString(name):
+		errs.add(path, "must consist of letters, digits, '_' and '-'")
+	default:
+		return true
+	}
+	return false
+}
+
+// ユーザー名・チーム名は予約された名前も使えない
+func validateAccountName(errs *ValidationErrors, path string, name string, maxLength int) {
+	if !validateIdentifier(errs, path, name, maxLength) {
+		return
+	}
+	if slices.ContainsFunc(reservedNames, func(v string) bool { return strings.EqualFold(v, name) }) {
+		errs.add(path, name+" is reserved")
+	}
+}
+
+func validateUserName(errs *ValidationErrors, path string, name string) {
+	validateAccountName(errs, path, name, maxUserNameLength)
+}
+
+func validateTeamName(errs *ValidationErrors, path string, name string) {
+	validateAccountName(errs, path, name, maxTeamNameLength)
+}
+
+// 表示名や説明は検証する前にこれを通す。見た目が同じで別の文字列になるのを防ぐ
+func normalizeText(s string) string {
+	return strings.TrimSpace(norm.NFC.String(s))
+}
+
+// 制御文字を含まず、maxLength 文字以内か。allowNewline なら改行とタブは許す
+func validateText(errs *ValidationErrors, path string, s string, maxLength int, allowNewline bool) {
+	if !utf8.ValidString(s) {
+		errs.add(path, "must be valid UTF-8")
+		return
+	}
+	if n := utf8.RuneCountInString(s); n > maxLength {
+		errs.add(path, fmt.Sprintf("must be at most %d characters", maxLength))
+		return
+	}
+	for _, r := range s {
+		if allowNewline && (r == '\n' || r == '\r' || r == '\t') {
+			continue
+		}
+		if unicode.IsControl(r) {
+			errs.add(path, "must not contain control characters")
+			return
+		}
+	}
+}
+
+func validateDisplayName(errs *ValidationErrors, path string, s string) {
+	if s == "" {
+		errs.add(path, "must not be empty")
+		return
+	}
+	validateText(errs, path, s, maxDisplayNameLength, false)
+}
+
+func validateDescription(errs *ValidationErrors, path string, s string) {
+	if s == "" {
+		errs.add(path, "must not be empty")
+		return
+	}
+	validateText(errs, path, s, maxDescriptionLength, true)
+}
+
+// タスク本体のフィールドを検証する。タスク編集系のエンドポイントでも使う
+func validateTaskFields(errs *ValidationErrors, name string, displayName string, statement string, submissionLimit int) {
+	// /api/tasks/:taskname に入るので、ユーザー名・チーム名と同じ文字だけにする (予約された名前は関係ない)
+	validateIdentifier(errs, "name", name, maxNameLength)
+	validateName(errs, "display_name", displayName)
+	if statement == "" {
+		errs.add("statement", "must not be empty")
+	}
+	if submissionLimit <= 0 {
+		errs.add("submission_limit", "must be positive")
+	}
+}
+
+// サブタスクを検証する。answers は (task_id, answer) で一意なので、seenAnswers でタスク全体の重複を見る
+func validateSubtaskRequest(errs *ValidationErrors, path string, subtask SubtaskRequest, seenAnswers map[string]string) {
+	validateName(errs, path+".name", subtask.Name)
+	validateName(errs, path+".display_name", subtask.DisplayName)
+	if subtask.Statement == "" {
+		errs.add(path+".statement", "must not be empty")
+	}
+	if len(subtask.Answers) == 0 {
+		errs.add(path+".answers", "must have at least one answer")
+	}
+	for i, answer := range subtask.Answers {
+		answerpath := fmt.Sprintf("%s.answers[%d]", path, i)
+		if answer.Answer == "" {
+			errs.add(answerpath+".answer", "must not be empty")
+		} else if len(answer.Answer) > maxAnswerLength {
+			errs.add(answerpath+".answer", fmt.Sprintf("must be at most %d bytes", maxAnswerLength))
+		} else if prev, ok := seenAnswers[answer.Answer]; ok {
+			errs.add(answerpath+".answer", "duplicates "+prev)
+		} else {
+			seenAnswers[answer.Answer] = answerpath
+		}
+		if answer.Score < 0 {
+			errs.add(answerpath+".score", "must not be negative")
+		}
+	}
+}
+
+func validateCreateTaskRequest(req CreateTaskRequest) ValidationErrors {
+	errs := ValidationErrors{}
+	validateTaskFields(&errs, req.Name, req.DisplayName, req.Statement, req.SubmissionLimit)
+
+	if len(req.Subtasks) == 0 {
+		errs.add("subtasks", "must have at least one subtask")
+	}
+	seenSubtasks := map[string]string{}
+	seenAnswers := map[string]string{}
+	for i, subtask := range req.Subtasks {
+		path := fmt.Sprintf("subtasks[%d]", i)
+		if prev, ok := seenSubtasks[subtask.Name]; ok && subtask.Name != "" {
+			errs.add(path+".name", "duplicates "+prev)
+		} else {
+			seenSubtasks[subtask.Name] = path + ".name"
+		}
+		validateSubtaskRequest(&errs, path, subtask, seenAnswers)
+	}
+	return errs
+}
@@ -1,3 +1,11 @@
+TRUNCATE TABLE `api_tokens`;
+TRUNCATE TABLE `sessions`;
+TRUNCATE TABLE `registration_allowlist`;
+TRUNCATE TABLE `registration_settings`;
+TRUNCATE TABLE `first_solves`;
+TRUNCATE TABLE `password_resets`;
+TRUNCATE TABLE `login_attempts`;
+TRUNCATE TABLE `login_lockouts`;
 TRUNCATE TABLE `users`;
 ALTER TABLE `users` AUTO_INCREMENT = 1;
 INSERT INTO `users` (`id`, `name`, `display_name`, `description`, `passhash`) VALUES
@@ -507,3 +515,5 @@ INSERT INTO `submissions` (`id`, `task_id`, `user_id`, `submitted_at`, `answer`)
 (307, 1, 51, '2024-03-26 18:05:06', '5'),
 (308, 2, 86, '2024-03-26 18:05:07', '5'),
 (309, 2, 37, '2024-03-26 18:05:08', '5');
+-- 初期データの提出は、送られてきた時刻に受け取ったことにする
+UPDATE `submissions` SET `received_at` = `submitted_at`;
@@ -8,12 +8,7 @@ RISUCON_DB_USER=${RISUCON_DB_USER:-risucon}
 RISUCON_DB_PASSWORD=${RISUCON_DB_PASSWORD:-risucon}
 RISUCON_DB_NAME=${RISUCON_DB_NAME:-risucontest}
 
-mysql -u"$RISUCON_DB_USER" \
-		-p"$RISUCON_DB_PASSWORD" \
-		--host "$RISUCON_DB_HOST" \
-		--port "$RISUCON_DB_PORT" \
-		"$RISUCON_DB_NAME" < 00_schema.sql
-
+# スキーマは migrations/ で管理していて、サーバー起動時に適用される
 mysql -u"$RISUCON_DB_USER" \
 		-p"$RISUCON_DB_PASSWORD" \
 		--host "$RISUCON_DB_HOST" \
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS `submissions`;
+DROP TABLE IF EXISTS `answers`;
+DROP TABLE IF EXISTS `subtasks`;
+DROP TABLE IF EXISTS `tasks`;
+DROP TABLE IF EXISTS `teams`;
+DROP TABLE IF EXISTS `users`;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS `login_attempts` (
+    `id` INT NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    `user_name` VARCHAR(255) NOT NULL,
+    `ip` VARCHAR(64) NOT NULL,
+    `user_agent` VARCHAR(512) NOT NULL,
+    `succeeded` BOOLEAN NOT NULL,
+    `reason` VARCHAR(32) NOT NULL,
+    `attempted_at` DATETIME NOT NULL,
+    INDEX `login_attempts_user_idx` (`user_name`, `attempted_at`)
+) ENGINE=InnoDB CHARACTER SET utf8mb4 COLLATE utf8mb4_bin;
+
+-- 失敗の回数は updated_at より後のものだけ数える (ロックしたときと解除したときに更新する)
+CREATE TABLE IF NOT EXISTS `login_lockouts` (
+    `user_name` VARCHAR(255) NOT NULL PRIMARY KEY,
+    `locked_until` DATETIME NOT NULL,
+    `updated_at` DATETIME NOT NULL
+) ENGINE=InnoDB CHARACTER SET utf8mb4 COLLATE utf8mb4_bin;
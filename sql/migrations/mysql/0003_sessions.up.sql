@@ -0,0 +1,12 @@
+-- id はクッキーに入っている値の SHA-256。DB が漏れてもそのままクッキーとしては使えない
+CREATE TABLE IF NOT EXISTS `sessions` (
+    `id` CHAR(64) NOT NULL PRIMARY KEY,
+    `user_name` VARCHAR(255) NOT NULL,
+    `data` TEXT NOT NULL,
+    `ip` VARCHAR(64) NOT NULL,
+    `user_agent` VARCHAR(512) NOT NULL,
+    `created_at` DATETIME NOT NULL,
+    `expires_at` DATETIME NOT NULL,
+    INDEX `sessions_user_idx` (`user_name`),
+    INDEX `sessions_expires_idx` (`expires_at`)
+) ENGINE=InnoDB CHARACTER SET utf8mb4 COLLATE utf8mb4_bin;
@@ -0,0 +1,11 @@
+-- トークンそのものは保存せず、SHA-256 だけを持つ。scopes はカンマ区切り
+CREATE TABLE IF NOT EXISTS `api_tokens` (
+    `id` INT NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    `user_name` VARCHAR(255) NOT NULL,
+    `name` VARCHAR(255) NOT NULL,
+    `token_hash` CHAR(64) NOT NULL,
+    `scopes` VARCHAR(255) NOT NULL,
+    `created_at` DATETIME NOT NULL,
+    UNIQUE `uniq_token_hash` (`token_hash`),
+    INDEX `api_tokens_user_idx` (`user_name`)
+) ENGINE=InnoDB CHARACTER SET utf8mb4 COLLATE utf8mb4_bin;
@@ -0,0 +1,3 @@
+-- official: 通常 / unofficial: 順位表に出すが順位は付けない (オープン参加など)
+-- disqualified: 失格。順位表の一番下に出す / hidden: 失格。順位表に出さない
+ALTER TABLE `teams` ADD COLUMN `status` VARCHAR(16) NOT NULL DEFAULT 'official';
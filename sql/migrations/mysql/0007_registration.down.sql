@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS `registration_allowlist`;
+DROP TABLE IF EXISTS `registration_settings`;
@@ -0,0 +1,9 @@
+-- サブタスクごとに、最初に満点を取ったチーム。提出から作り直せるので、採点し直したときは作り直す
+CREATE TABLE IF NOT EXISTS `first_solves` (
+    `subtask_id` INT NOT NULL PRIMARY KEY,
+    `task_id` INT NOT NULL,
+    `team_id` INT NOT NULL,
+    `user_id` INT NOT NULL,
+    `solved_at` DATETIME NOT NULL,
+    INDEX `first_solves_task_idx` (`task_id`)
+) ENGINE=InnoDB CHARACTER SET utf8mb4 COLLATE utf8mb4_bin;
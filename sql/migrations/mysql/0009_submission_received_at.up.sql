@@ -0,0 +1,4 @@
+-- 提出をサーバーが受け取った時刻。submitted_at はクライアントが送ってくるので、first blood はこちらで決める
+-- 既にある提出には、ほかに手がかりがないので submitted_at を入れる
+ALTER TABLE `submissions` ADD COLUMN `received_at` DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00';
+UPDATE `submissions` SET `received_at` = `submitted_at` WHERE `received_at` = '1970-01-01 00:00:00';
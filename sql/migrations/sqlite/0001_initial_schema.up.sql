@@ -0,0 +1,63 @@
+CREATE TABLE IF NOT EXISTS `users` (
+    `id` INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    `name` VARCHAR(255) NOT NULL,
+    `display_name` VARCHAR(255) NOT NULL,
+    `description` TEXT NOT NULL,
+    `passhash` VARCHAR(255) NOT NULL,
+    UNIQUE (`name`)
+);
+
+CREATE TABLE IF NOT EXISTS `teams` (
+    `id` INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    `name` VARCHAR(255) NOT NULL,
+    `display_name` VARCHAR(255) NOT NULL,
+    `leader_id` INT NOT NULL,
+    `member1_id` INT DEFAULT -1 NOT NULL,
+    `member2_id` INT DEFAULT -1 NOT NULL,
+    `description` TEXT NOT NULL,
+    `invitation_code` VARCHAR(255) NOT NULL,
+    UNIQUE (`name`)
+);
+
+CREATE TABLE IF NOT EXISTS `tasks` (
+    `id` INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    `name` VARCHAR(255) NOT NULL,
+    `display_name` VARCHAR(255) NOT NULL,
+    `statement` TEXT NOT NULL,
+    `submission_limit` INT NOT NULL,
+    UNIQUE (`name`)
+);
+
+CREATE TABLE IF NOT EXISTS `subtasks` (
+    `id` INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    `name` VARCHAR(255) NOT NULL,
+    `display_name` VARCHAR(255) NOT NULL,
+    `task_id` INT NOT NULL,
+    `statement` TEXT NOT NULL,
+    UNIQUE (`task_id`, `name`)
+);
+CREATE INDEX IF NOT EXISTS `subtasks_sub_idx` ON `subtasks` (`task_id`);
+
+CREATE TABLE IF NOT EXISTS `answers` (
+    `id` INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    `task_id` INT NOT NULL,
+    `subtask_id` INT NOT NULL,
+    `answer` VARCHAR(255) NOT NULL,
+    `score` INT NOT NULL,
+    UNIQUE (`task_id`, `answer`)
+);
+CREATE INDEX IF NOT EXISTS `answers_ans_idx` ON `answers` (`subtask_id`);
+
+CREATE TABLE IF NOT EXISTS `submissions` (
+    `id` INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    `task_id` INT NOT NULL,
+    `user_id` INT NOT NULL,
+    `submitted_at` DATETIME NOT NULL,
+    `answer` VARCHAR(255) NOT NULL,
+    `subtask_id` INT NOT NULL DEFAULT -1,
+    `score` INT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS `submissions_sub_idx` ON `submissions` (`task_id`, `user_id`, `answer`);
+CREATE INDEX IF NOT EXISTS `submissions_sub_idx2` ON `submissions` (`subtask_id`, `user_id`);
+CREATE INDEX IF NOT EXISTS `submissions_sub_idx3` ON `submissions` (`task_id`, `user_id`, `subtask_id`, `score` DESC);
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS `login_attempts` (
+    `id` INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    `user_name` VARCHAR(255) NOT NULL,
+    `ip` VARCHAR(64) NOT NULL,
+    `user_agent` VARCHAR(512) NOT NULL,
+    `succeeded` BOOLEAN NOT NULL,
+    `reason` VARCHAR(32) NOT NULL,
+    `attempted_at` DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS `login_attempts_user_idx` ON `login_attempts` (`user_name`, `attempted_at`);
+
+-- 失敗の回数は updated_at より後のものだけ数える (ロックしたときと解除したときに更新する)
+CREATE TABLE IF NOT EXISTS `login_lockouts` (
+    `user_name` VARCHAR(255) NOT NULL PRIMARY KEY,
+    `locked_until` DATETIME NOT NULL,
+    `updated_at` DATETIME NOT NULL
+);
@@ -0,0 +1,11 @@
+-- トークンそのものは保存せず、SHA-256 だけを持つ。scopes はカンマ区切り
+CREATE TABLE IF NOT EXISTS `api_tokens` (
+    `id` INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    `user_name` VARCHAR(255) NOT NULL,
+    `name` VARCHAR(255) NOT NULL,
+    `token_hash` CHAR(64) NOT NULL,
+    `scopes` VARCHAR(255) NOT NULL,
+    `created_at` DATETIME NOT NULL,
+    UNIQUE (`token_hash`)
+);
+CREATE INDEX IF NOT EXISTS `api_tokens_user_idx` ON `api_tokens` (`user_name`);
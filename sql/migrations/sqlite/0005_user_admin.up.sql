@@ -0,0 +1,11 @@
+-- 無効にしたユーザーはログインも提出もできない
+ALTER TABLE `users` ADD COLUMN `disabled` BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- 管理者が発行したパスワード再設定用のトークン。ユーザーごとに最新の 1 つだけ有効
+CREATE TABLE IF NOT EXISTS `password_resets` (
+    `user_id` INTEGER NOT NULL PRIMARY KEY,
+    `token_hash` CHAR(64) NOT NULL,
+    `created_at` DATETIME NOT NULL,
+    `expires_at` DATETIME NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `uniq_password_reset_token` ON `password_resets` (`token_hash`);
@@ -0,0 +1,13 @@
+-- 管理者が変更した登録の設定。id = 1 の 1 行だけ。なければ設定ファイルの値を使う
+CREATE TABLE IF NOT EXISTS `registration_settings` (
+    `id` INTEGER NOT NULL PRIMARY KEY,
+    `mode` VARCHAR(16) NOT NULL,
+    `code` VARCHAR(255) NOT NULL,
+    `updated_at` DATETIME NOT NULL
+);
+
+-- mode が allowlist のときに登録できる名前
+CREATE TABLE IF NOT EXISTS `registration_allowlist` (
+    `name` VARCHAR(255) NOT NULL PRIMARY KEY,
+    `created_at` DATETIME NOT NULL
+);
@@ -0,0 +1,9 @@
+-- サブタスクごとに、最初に満点を取ったチーム。提出から作り直せるので、採点し直したときは作り直す
+CREATE TABLE IF NOT EXISTS `first_solves` (
+    `subtask_id` INTEGER NOT NULL PRIMARY KEY,
+    `task_id` INTEGER NOT NULL,
+    `team_id` INTEGER NOT NULL,
+    `user_id` INTEGER NOT NULL,
+    `solved_at` DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS `first_solves_task_idx` ON `first_solves` (`task_id`);
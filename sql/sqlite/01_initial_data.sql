@@ -0,0 +1,513 @@
+DELETE FROM `api_tokens`;
+DELETE FROM `sessions`;
+DELETE FROM `registration_allowlist`;
+DELETE FROM `registration_settings`;
+DELETE FROM `first_solves`;
+DELETE FROM `password_resets`;
+DELETE FROM `login_attempts`;
+DELETE FROM `login_lockouts`;
+DELETE FROM `users`;
+INSERT INTO `users` (`id`, `name`, `display_name`, `description`, `passhash`) VALUES
+(1, 'admin', '管理者', '管理者アカウントです。', '8c6976e5b5410415bde908bd4dee15dfb167a9c873fc4bb8a81f6f2ab448a918'),
+(2, 'risucon', 'risucon', 'テスト用アカウントです。', 'dda4299a6bfc5d19226053a577aa72d93c62ebd27c699837e8efd0f9a1d1ebaf'),
+(3, 'shuuhakaseL', 'しゅーはかせえる', '高校3年のしゅーはかせえるです。よろしくお願いします。', '42cbebad087dec80848807a6bfce628cb866655f3695d34c7b519848c7b79eb5'),
+(4, 'kyaashishoP', 'きゃーししょうぴー', '高校1年のきゃーししょうぴーです。よろしくお願いします。', '97be4d63b7b3fbfac9dec27960ede4a09bc241cd5ddc441a878921c10702e418'),
+(5, 'bishiL', 'びしえる', '高校3年のびしえるです。よろしくお願いします。', 'b3573f83bec205bc325af698c130c083b9eb8bb58c42958a11d79281c3647b98'),
+(6, 'pyuusan7', 'ぴゅーさんせぶん', '高校1年のぴゅーさんせぶんです。よろしくお願いします。', 'b0ce64c04fe1d3506ef7f5fb5889f3278012f51b9f1f53999cb36785722de00b'),
+(7, 'hyaarabbitI', 'ひゃーらびっとあい', '中学1年のひゃーらびっとあいです。よろしくお願いします。', 'da5fc579732f33590cfd9f07606e04fc13d165856d3bab51c0378a9b1d961a44'),
+(8, 'akunZ', 'あくんぜっと', '中学2年のあくんぜっとです。よろしくお願いします。', 'b4fbf3265a4fedb855f65cfc037c23a0b06af0d1223e3641d560a4c7c6745cc0'),
+(9, 'naasanH', 'なーさんえいち', '中学3年のなーさんえいちです。よろしくお願いします。', 'de92fe8f0bc4c74f988d9b10cf180aa39a28d07f7446b089217f36c4c4492880'),
+(10, 'pyaasamaN', 'ぴゃーさまえぬ', '中学2年のぴゃーさまえぬです。よろしくお願いします。', '8b12d84669a2db213301ad7620c5f7c2d479b235e5dc817ca8d09384e9ce3364'),
+(11, 'roodog2', 'ろーどっぐつー', '中学3年のろーどっぐつーです。よろしくお願いします。', '36fb664099eed03bdf4f5ea7f4c6cc7bde89ce4875b632da65d8eecf290fcbf4'),
+(12, 'moodono9', 'もーどのないん', '中学3年のもーどのないんです。よろしくお願いします。', '807940a370bafb98a33d50b45c82376687cc78ecc8260ee21027c542e183efcc'),
+(13, 'iisanH', 'いーさんえいち', '高校3年のいーさんえいちです。よろしくお願いします。', 'be7ef1c3aa312b733111a53c0d985d512c8a4ef2c5b8a42c8400e4a7e075a1a3'),
+(14, 'beechanG', 'べーちゃんじー', '中学1年のべーちゃんじーです。よろしくお願いします。', 'bd614ec74a725c05a620bc49010c48b9118db6437b5dd68d03a654f236d95205'),
+(15, 'tsuushishoC', 'つーししょうしー', '高校3年のつーししょうしーです。よろしくお願いします。', '3d5e2cf15e3df3ccb8756d83b3c12d13b6eee7846e7b267f95143db024b713d4'),
+(16, 'moorabbitP', 'もーらびっとぴー', '中学3年のもーらびっとぴーです。よろしくお願いします。', '6df291c823d4253ef8d38835829a9581a2ba90a04a12e9fa22edb324c8d59061'),
+(17, 'yoorabbit6', 'よーらびっとしっくす', '中学1年のよーらびっとしっくすです。よろしくお願いします。', 'c8d8aaaedabcf6b47ac2390946b2de836e6cf2f3be901457a8e7515acb920c70'),
+(18, 'zaahakaseS', 'ざーはかせえす', '中学1年のざーはかせえすです。よろしくお願いします。', 'cdeaca536d062ffac0d3880f6164e120a08cf62d7d460c822db15d0d0de42af4'),
+(19, 'ryoodogG', 'りょーどっぐじー', '中学1年のりょーどっぐじーです。よろしくお願いします。', '3d97754da052f9ab14bb0b7aefa46872ca3ad75b831a16273f9d339669ecb784'),
+(20, 'idonoF', 'いどのえふ', '中学2年のいどのえふです。よろしくお願いします。', 'c973f55318e12818c70f5165426df8d92353d3746bd571eb4f393f20eb8820c1'),
+(21, 'pyuusenseiB', 'ぴゅーせんせいびー', '中学1年のぴゅーせんせいびーです。よろしくお願いします。', '8b03ce27e043a6362410c60d7f8378f9961bb80697d1b5b1ec578a752357e0d1'),
+(22, 'woosensei8', 'をーせんせいえいと', '高校2年のをーせんせいえいとです。よろしくお願いします。', '241139f43bdaefdc3792ed3f85141a1896f949a555e3e3d4497c07b7f7ca14e5'),
+(23, 'yarabbitL', 'やらびっとえる', '中学3年のやらびっとえるです。よろしくお願いします。', '9776a66f03fc0404b5c8757bdff34ae83672536340f82ebb7bcc6238ab44e246'),
+(24, 'padogI', 'ぱどっぐあい', '高校2年のぱどっぐあいです。よろしくお願いします。', '0962d4141c3aef818edd031f98d12ab1328f91f287b1bbe3c25a4969b91df941'),
+(25, 'fuuhakase1', 'ふーはかせわん', '高校3年のふーはかせわんです。よろしくお願いします。', '09f2da7946c763ff3131ae3030a176c8506da3b7650d42a8f9320a9d81a1246d'),
+(26, 'keesanL', 'けーさんえる', '中学1年のけーさんえるです。よろしくお願いします。', '26fbb63871b4df77de5208dff22997ee93da47a66b993174a88195551212e3a0'),
+(27, 'jaasenseiP', 'じゃーせんせいぴー', '中学1年のじゃーせんせいぴーです。よろしくお願いします。', '11714f4c99302bd9cf2325a00be64ddb5d9aece6d880e38650b9f8776caed33d'),
+(28, 'shuucatR', 'しゅーきゃっとあーる', '中学1年のしゅーきゃっとあーるです。よろしくお願いします。', '0cf2d4e457c2c754f86620ae98bf5bcbdc8fe1269f67326630830722c0a0765c'),
+(29, 'gyaashisho7', 'ぎゃーししょうせぶん', '高校2年のぎゃーししょうせぶんです。よろしくお願いします。', '8b5dc7aa504fb18ac64091336280973d45d1e9bf3e3a05547e8b18f0043ffdbb'),
+(30, 'chiichan8', 'ちーちゃんえいと', '中学3年のちーちゃんえいとです。よろしくお願いします。', '4eee80dc190c60f0749dfc2d23b218df812bfcb183c8acf474b1083eac913ab4'),
+(31, 'chaasenseiP', 'ちゃーせんせいぴー', '中学3年のちゃーせんせいぴーです。よろしくお願いします。', '785dbc79b0fc36ffe6c14619de129408c96881216df1d9de033bd187694d2226'),
+(32, 'mihakaseP', 'みはかせぴー', '中学3年のみはかせぴーです。よろしくお願いします。', '71c899056ce29dd25b44154a452918712ef90ff443772b4569af5271229efcce'),
+(33, 'teesanJ', 'てーさんじぇー', '中学3年のてーさんじぇーです。よろしくお願いします。', 'ed6e5889a7ceea8b6dbee57d24e647ab9e05bda4b2841f1a04b022197d2422c8'),
+(34, 'gesenseiD', 'げせんせいでぃー', '中学1年のげせんせいでぃーです。よろしくお願いします。', '1c24122405772683204a61866ab3cb9977b4624b8767732b81ef1d3a1db2a387'),
+(35, 'rochan7', 'ろちゃんせぶん', '中学2年のろちゃんせぶんです。よろしくお願いします。', '4af6d66b163867277ed0de0813fc8f5a6c315f33997b2fc1b0b13aeb5c825f55'),
+(36, 'nushiP', 'ぬしぴー', '中学2年のぬしぴーです。よろしくお願いします。', 'ec02bb098def0473c60db0f10d40da57969f9709e8083c7b19d39795350d7d9a'),
+(37, 'booshishoN', 'ぼーししょうえぬ', '中学2年のぼーししょうえぬです。よろしくお願いします。', 'a91f2e3877b049c1f46b0ce67b2953df9a317f86db3f095818ade497f52c6e60'),
+(38, 'yaasenseiH', 'やーせんせいえいち', '中学2年のやーせんせいえいちです。よろしくお願いします。', '5404f4b13fc4819155578b501b6ead1d55747767d4962ca35d066ab5df8363fc'),
+(39, 'kyaakunZ', 'きゃーくんぜっと', '中学3年のきゃーくんぜっとです。よろしくお願いします。', 'f73f049bf2dc839d647b4b7e9853b6d8a02f5c75e8833e62af22349717f7c8fc'),
+(40, 'rudogE', 'るどっぐいー', '高校2年のるどっぐいーです。よろしくお願いします。', '3edd17913066f89fa375bebf64bec6b16c89603ac05dcd4750a65525ef542cc3'),
+(41, 'ryoodogC', 'りょーどっぐしー', '高校2年のりょーどっぐしーです。よろしくお願いします。', '8c46996209d3ced2de8fdf5913ed7dad3c2877af5d85e2978b3928ef97eb1a0f'),
+(42, 'dasanN', 'ださんえぬ', '中学2年のださんえぬです。よろしくお願いします。', '865a3af300dbcf985c419f86d30ef35f5c3237c22a43c9876da59056d9b65834'),
+(43, 'aasan1', 'あーさんわん', '高校1年のあーさんわんです。よろしくお願いします。', '096ad23c839a0ab2424306a9379ceb3ef6d341d1985693a0b9af2554cabd3ccf'),
+(44, 'moohakaseY', 'もーはかせわい', '中学1年のもーはかせわいです。よろしくお願いします。', 'd7172e1b1c6ef066dd4e3c22f1630f8bfd224f8c9166dc9cf7f3e53900205fe3'),
+(45, 'hoshisho7', 'ほししょうせぶん', '中学3年のほししょうせぶんです。よろしくお願いします。', 'ccf7b4a4588707bcb612d286f1137b7e1c7f7cea58766aab953aa09f8f7a11f4'),
+(46, 'kushiB', 'くしびー', '中学2年のくしびーです。よろしくお願いします。', '8e0510cc4b6dd5b20a2e74bb27c0b786ebcd4ecb247e3fc5a34b080681c4c686'),
+(47, 'nihakase6', 'にはかせしっくす', '中学1年のにはかせしっくすです。よろしくお願いします。', '261b73571532e6d38f084cba5df8b5301ffb04b806479c2377b70245b9211574'),
+(48, 'ryuuhakase1', 'りゅーはかせわん', '中学1年のりゅーはかせわんです。よろしくお願いします。', '7f88617ea203ea368b5e75d3f0e509ef91739b2accaece30f090fbff837a49a3'),
+(49, 'yoochanL', 'よーちゃんえる', '高校2年のよーちゃんえるです。よろしくお願いします。', 'a48cf17ad74d5eb58ad931e004d13668996cdfe3985b6e5de52124c236edef6c'),
+(50, 'neekunI', 'ねーくんあい', '高校3年のねーくんあいです。よろしくお願いします。', '50b773e697f695be1729dc4bd2e0f162a1059e43d92584c12f69b56f8aa85071'),
+(51, 'zeesamaD', 'ぜーさまでぃー', '高校3年のぜーさまでぃーです。よろしくお願いします。', '31610eac61b55be53d151a7523891121e2944feb44da9c28198e994172327f3b'),
+(52, 'fushi3', 'ふしすりー', '高校3年のふしすりーです。よろしくお願いします。', '88384ff8f1ee23d4d0f0d91acecdab05f86be267927a85a72cae6b232793931c'),
+(53, 'oodogD', 'おーどっぐでぃー', '中学3年のおーどっぐでぃーです。よろしくお願いします。', '5f6b80ed08c97458ea56fadcaba00314ca714474d2e4082d872e26e09567f71b'),
+(54, 'kocatN', 'こきゃっとえぬ', '中学2年のこきゃっとえぬです。よろしくお願いします。', '12e942db801cd807624bbfc55bd5342285d106912e2f325e1c1757b53d5873f2'),
+(55, 'pudono7', 'ぷどのせぶん', '高校1年のぷどのせぶんです。よろしくお願いします。', 'a5601127e4bd4cbb1a3c145f0e0a7b10850e7169d8e6113808d1c3b4664f3cc9'),
+(56, 'paasanX', 'ぱーさんえっくす', '中学2年のぱーさんえっくすです。よろしくお願いします。', '4a4f463d68026db13aa0a631e5f898c092b3ea2adf207b246892472913f8bbbb'),
+(57, 'pyaashiO', 'ぴゃーしおー', '中学2年のぴゃーしおーです。よろしくお願いします。', '755e5c1b8fc643c7450193dc882ea4b2cf35fb2c08996fdaf7b4e0099c384144'),
+(58, 'daashishoX', 'だーししょうえっくす', '中学1年のだーししょうえっくすです。よろしくお願いします。', '7fbc929bf9cffb1f857fafd12faee3c8ad8fabdba0e7cddb1189ac80c8d87fed'),
+(59, 'kyoosenseiA', 'きょーせんせいえー', '高校3年のきょーせんせいえーです。よろしくお願いします。', 'd33a9b759b1fb4fac1e0ee47437a2e51486555df4ef5dddb69d2056c1af064ac'),
+(60, 'muuhakaseB', 'むーはかせびー', '高校2年のむーはかせびーです。よろしくお願いします。', 'd68b59cc824a66f02787b5c1835c101724499b2db1725198630ef5eef6b030dc'),
+(61, 'usanT', 'うさんてぃー', '高校2年のうさんてぃーです。よろしくお願いします。', '811a71202bab3c0d0e98d8d7a9bac6d612cbaad470404bc8380a44a0f2fb556a'),
+(62, 'ooshishoR', 'おーししょうあーる', '中学2年のおーししょうあーるです。よろしくお願いします。', '765491325af68d593394c9f0cdb69ecc69cc0fb785efea34145784ba06f855ab'),
+(63, 'myaashishoV', 'みゃーししょうぶい', '高校1年のみゃーししょうぶいです。よろしくお願いします。', 'bd688b71373ddf38cc4421348d1feef9430b1ed01629d4a953e84a42d03b75dd'),
+(64, 'chuuhakaseQ', 'ちゅーはかせきゅー', '中学1年のちゅーはかせきゅーです。よろしくお願いします。', 'adeb6790554c84cdab41ee5badf2a92b3ab5e6af4b603e1886196b36de9e5f84'),
+(65, 'seedono8', 'せーどのえいと', '高校2年のせーどのえいとです。よろしくお願いします。', 'c33a3b270bb9430d29b017bb9a551f4b6135f6c1d313bd20c7ac3529e6d2d827'),
+(66, 'ryuusan8', 'りゅーさんえいと', '中学1年のりゅーさんえいとです。よろしくお願いします。', '174e6cc52ef0a97e8ca865845afa19aaa38b04b386168a0ea84228906731d73a'),
+(67, 'heshiU', 'へしゆー', '中学3年のへしゆーです。よろしくお願いします。', '4d7da933b4b0d61ab188157c2559811d4d0caf6ccbf3113a62fef23b7d261bc8'),
+(68, 'gaasan7', 'がーさんせぶん', '高校2年のがーさんせぶんです。よろしくお願いします。', '4a7bb72ad91a6d4482269eaff8903a33c73cfe995ececa892700a9e68b804f2e'),
+(69, 'barabbit5', 'ばらびっとふぁいぶ', '高校1年のばらびっとふぁいぶです。よろしくお願いします。', '47e1b1ac8b32b1850d0b900833f12f343fd01153bb0b8f68e328a67cfde7d2eb'),
+(70, 'choohakaseL', 'ちょーはかせえる', '高校1年のちょーはかせえるです。よろしくお願いします。', '8813addfa096085ccd7a35967fd6673b9cf2c3a87b0fd149e99bc9a8033b815b'),
+(71, 'icat7', 'いきゃっとせぶん', '高校3年のいきゃっとせぶんです。よろしくお願いします。', 'e4f37c20f7f72f966998f5c353ced8c88a798679c715703e018b5b1bc154edc7'),
+(72, 'pyuudono6', 'ぴゅーどのしっくす', '高校2年のぴゅーどのしっくすです。よろしくお願いします。', 'c414ba670430a06d0776945b7e7ede21ea828cdf977ff6eb41c32d40e9872979'),
+(73, 'sekun3', 'せくんすりー', '中学3年のせくんすりーです。よろしくお願いします。', '89aea2ee7c914f0e4863f6cfe557c82aee382183abb0e83b1192045a3af1376f'),
+(74, 'gishiP', 'ぎしぴー', '高校1年のぎしぴーです。よろしくお願いします。', '6f063b6d3765e108a18710a65cc6bdb56c20a5d3315764afe4429829429bc26a'),
+(75, 'myoohakaseF', 'みょーはかせえふ', '中学2年のみょーはかせえふです。よろしくお願いします。', '8b8516938fcdc1c3550dad2c8ad775592b08d34a5449a766686bf375183372ac'),
+(76, 'ryaarabbitO', 'りゃーらびっとおー', '高校1年のりゃーらびっとおーです。よろしくお願いします。', '8423b0d7b649ed05aa2c7c989751f6de2c03cdc20a5851ba285411e4dbdff909'),
+(77, 'kyaashishoL', 'きゃーししょうえる', '中学1年のきゃーししょうえるです。よろしくお願いします。', '99ec44fc859cafbc348a0d915226ca4da8e76512b04682d89ca3355dcc103f8b'),
+(78, 'gichan3', 'ぎちゃんすりー', '高校2年のぎちゃんすりーです。よろしくお願いします。', '150261967de4df0b22e687d45b167b2665441aac173b9f31026b321fc94ec6a4'),
+(79, 'eerabbitR', 'えーらびっとあーる', '高校1年のえーらびっとあーるです。よろしくお願いします。', 'fcc3561f17370e0774b443ad170ed54d24f0d2ad1e9c8b2d6940fe589fbe565c'),
+(80, 'pyaasamaF', 'ぴゃーさまえふ', '中学3年のぴゃーさまえふです。よろしくお願いします。', '72f0c46b8122442c765c78a261fd4f93cbe65fd44dd179964070b5c7ed669913'),
+(81, 'giisanR', 'ぎーさんあーる', '高校3年のぎーさんあーるです。よろしくお願いします。', '10d0f498532ede4d7dcb5a16fd965e2bf7e0c28fdb45d96508dcc205d9351f26'),
+(82, 'saachanW', 'さーちゃんだぶりゅー', '高校3年のさーちゃんだぶりゅーです。よろしくお願いします。', '31f1007feba73a630a8bf2a34b943ae8ec964e4d334709ace066ad11feb42c10'),
+(83, 'pishiE', 'ぴしいー', '高校3年のぴしいーです。よろしくお願いします。', '3bdc9b54bb9c2f4af5cfc3954629ff18f5df80e9419353104b0a8547032560be'),
+(84, 'shaakunK', 'しゃーくんけー', '高校2年のしゃーくんけーです。よろしくお願いします。', '501b3566490e725d6a03ca274ab5350c61c129d0170813882f23940619b8a096'),
+(85, 'rihakase0', 'りはかせぜろ', '高校2年のりはかせぜろです。よろしくお願いします。', '501be00053418fdf0e64f80c9ff9e7b56bd6f9de632a811829d8420a155530c9'),
+(86, 'haacatT', 'はーきゃっとてぃー', '高校2年のはーきゃっとてぃーです。よろしくお願いします。', '35c9cfd5fe0b7185a0190d02f455fe872f31444b16ab4f2ffeb5fc0aa224070b'),
+(87, 'miichanR', 'みーちゃんあーる', '中学1年のみーちゃんあーるです。よろしくお願いします。', '54f68eb76ae2e8f6c5791ef470fe7df7e2e0b7b3777ef6af2d5e718b1b39472d'),
+(88, 'tasenseiE', 'たせんせいいー', '高校2年のたせんせいいーです。よろしくお願いします。', '6b552085797b9293266e1acf392f5c79dd9889492d127913abef01ff19c2e64a'),
+(89, 'nushi9', 'ぬしないん', '中学2年のぬしないんです。よろしくお願いします。', '0a36adf1289e3bbacb45d74b2e6d909c1f39408fc9597380666aa1a5b188297f'),
+(90, 'zaadono9', 'ざーどのないん', '高校2年のざーどのないんです。よろしくお願いします。', '1ae05723653e91b7bb25143ac94175221903791e50f555dd60b3babd9686f26e'),
+(91, 'piishi9', 'ぴーしないん', '中学1年のぴーしないんです。よろしくお願いします。', '60970abb00f5e9a93f6951e4e5fce47ac3208385123ec26a7a0e27bd1daa6b8e'),
+(92, 'wakunW', 'わくんだぶりゅー', '高校1年のわくんだぶりゅーです。よろしくお願いします。', '0601baf88a9a6769b0c6052e8e20c7c09768694ed3d9f27dc934c1f7ce10f562'),
+(93, 'chaakunD', 'ちゃーくんでぃー', '中学1年のちゃーくんでぃーです。よろしくお願いします。', '9a7cb47f8cfafefde901c5471108725831d4e6970541ce4f438d67678c8e26f8'),
+(94, 'nuhakaseK', 'ぬはかせけー', '高校3年のぬはかせけーです。よろしくお願いします。', '0986c13b0ba9f52237e5ca4fe1b76f157267816bd50bc240078eb3aab88782dd'),
+(95, 'haarabbitA', 'はーらびっとえー', '中学1年のはーらびっとえーです。よろしくお願いします。', '7b0af3ed9716ced83ce8e84cf151b83aced0dfe0dd70a739d05360ae185f4abb'),
+(96, 'pisenseiF', 'ぴせんせいえふ', '高校2年のぴせんせいえふです。よろしくお願いします。', 'fb699c6cb36218e1adfaa7bcb398254fa99005607c060572f516dd3297b32ef1'),
+(97, 'nyaachanQ', 'にゃーちゃんきゅー', '中学3年のにゃーちゃんきゅーです。よろしくお願いします。', '17436d8e2c536f0d81863ed23ad499171423e48b10ef81c6a51a6e13611aef5b'),
+(98, 'kohakaseW', 'こはかせだぶりゅー', '中学3年のこはかせだぶりゅーです。よろしくお願いします。', 'b1b99674bc9e2a603315687b9fff783982c66eb4425f36394025eb9f351637c1'),
+(99, 'naasanW', 'なーさんだぶりゅー', '中学1年のなーさんだぶりゅーです。よろしくお願いします。', '9d81d8b6e3030553a432cf69dd5121607eb1a196b12ef4fd77ec0345b7361ba6'),
+(100, 'chaasamaD', 'ちゃーさまでぃー', '高校1年のちゃーさまでぃーです。よろしくお願いします。', '4c82625eb0fa23c882df632766d84631fe501b4a661e3eea08adfca3d02c3286'),
+(101, 'mesenseiX', 'めせんせいえっくす', '中学2年のめせんせいえっくすです。よろしくお願いします。', 'fccdd1f4d031c4646298ad40970caae4fbf674b61f0556c69f7ed3a45d499a9b'),
+(102, 'misanG', 'みさんじー', '中学2年のみさんじーです。よろしくお願いします。', 'fdf2c54ca4ee69a0ac590c38bf64292386fc87be4f31c97f7cdb6f30cd4cdb68'),
+(103, 'dadono4', 'だどのふぉー', '中学2年のだどのふぉーです。よろしくお願いします。', '07d374f642cd1c893bc22ccea48c4e7ed69eba199d73a16bff56b33359f1fccb'),
+(104, 'zuchan0', 'ずちゃんぜろ', '高校1年のずちゃんぜろです。よろしくお願いします。', '61dd46eec98ed8bbd4be55bdfb380f60f62d1e4847843323e835eeefccde48f5');
+DELETE FROM `teams`;
+INSERT INTO `teams` (`id`, `name`, `display_name`, `leader_id`, `member1_id`, `member2_id`, `description`, `invitation_code`) VALUES
+(1, 'risucon', 'risucon', 2, -1, -1, 'テスト用チームです。', '72697375636f6e21'),
+(2, 'miraclekarasugun', 'ミラクルカラス軍', 3, 4, -1, 'チーム「ミラクルカラス軍」です。よろしくお願いします。', 'ecfcf3b9601d97c2'),
+(3, 'kishihamusutarenmei', '騎士ハムスター連盟', 5, -1, -1, 'チーム「騎士ハムスター連盟」です。よろしくお願いします。', 'b3cc606c652e0469'),
+(4, 'maoukamonohashikumiai', '魔王カモノハシ組合', 6, 7, -1, 'チーム「魔王カモノハシ組合」です。よろしくお願いします。', '21a4ebbd68e54659'),
+(5, 'miraclemorumottocircle', 'ミラクルモルモットサークル', 8, 9, 10, 'チーム「ミラクルモルモットサークル」です。よろしくお願いします。', '586327790b94c982'),
+(6, 'saikyofukurougundan', '最強フクロウ軍団', 11, 12, -1, 'チーム「最強フクロウ軍団」です。よろしくお願いします。', 'b0a39068ee56a249'),
+(7, 'shinobinezumitoyukainanakamatachi', '忍びネズミと愉快な仲間たち', 13, -1, -1, 'チーム「忍びネズミと愉快な仲間たち」です。よろしくお願いします。', 'f67aa7f49c0bfd1b'),
+(8, 'supermorumottorengou', 'スーパーモルモット連合', 14, 15, 16, 'チーム「スーパーモルモット連合」です。よろしくお願いします。', 'f5bd6a8b7ca4bfc1'),
+(9, 'maoumoguranado', '魔王モグラなど', 17, 18, 19, 'チーム「魔王モグラなど」です。よろしくお願いします。', 'e42ba741b3bfeb6c'),
+(10, 'hyperfuramingocircle', 'ハイパーフラミンゴサークル', 20, 21, -1, 'チーム「ハイパーフラミンゴサークル」です。よろしくお願いします。', 'bb430eac43b5d974'),
+(11, 'saikyomimizukutoyukainanakamatachi', '最強ミミズクと愉快な仲間たち', 22, 23, 24, 'チーム「最強ミミズクと愉快な仲間たち」です。よろしくお願いします。', '94f598c6e90f7f93'),
+(12, 'tensainezumibu', '天才ネズミ部', 25, -1, -1, 'チーム「天才ネズミ部」です。よろしくお願いします。', 'c7132fb3ade2564e'),
+(13, 'shinobimorumottoclub', '忍びモルモットクラブ', 26, -1, -1, 'チーム「忍びモルモットクラブ」です。よろしくお願いします。', 'b9aa64b41c4440d6'),
+(14, 'specialkamonohashiguild', 'スペシャルカモノハシギルド', 27, -1, -1, 'チーム「スペシャルカモノハシギルド」です。よろしくお願いします。', '1829334864de79b8'),
+(15, 'yuushahamusutanonakamatachi', '勇者ハムスターの仲間たち', 28, 29, -1, 'チーム「勇者ハムスターの仲間たち」です。よろしくお願いします。', '36049f14a1c69c36'),
+(16, 'ninjasuzumenokai', '忍者スズメの会', 30, 31, -1, 'チーム「忍者スズメの会」です。よろしくお願いします。', '4405ebfd078e06c0'),
+(17, 'pasokonryokuwotakamerunokai', 'パソコン力を高めるの会', 32, 33, 34, 'チーム「パソコン力を高めるの会」です。よろしくお願いします。', '92e27643503cd47d'),
+(18, 'fantasticperikankyoukai', 'ファンタスティックペリカン協会', 35, -1, -1, 'チーム「ファンタスティックペリカン協会」です。よろしくお願いします。', '14575fa7d72a3e12'),
+(19, 'specialmimizukugumi', 'スペシャルミミズク組', 36, -1, -1, 'チーム「スペシャルミミズク組」です。よろしくお願いします。', 'b5beca2b00566081'),
+(20, 'mysticalmomongagun', 'ミスティカルモモンガ軍', 37, 38, -1, 'チーム「ミスティカルモモンガ軍」です。よろしくお願いします。', '09d8e149f6a7ddd9'),
+(21, 'specialfukurounokai', 'スペシャルフクロウの会', 39, -1, -1, 'チーム「スペシャルフクロウの会」です。よろしくお願いします。', '3ec3d3a3708bd8aa'),
+(22, 'majoinucircle', '魔女イヌサークル', 40, 41, -1, 'チーム「魔女イヌサークル」です。よろしくお願いします。', '48f9c618a74d9abe'),
+(23, 'shinobiperikandoumei', '忍びペリカン同盟', 42, 43, 44, 'チーム「忍びペリカン同盟」です。よろしくお願いします。', '07adf76e4da29b95'),
+(24, 'tenshinoperikanclub', '天使のペリカンクラブ', 45, 46, -1, 'チーム「天使のペリカンクラブ」です。よろしくお願いします。', 'ee544a71e7638e9f'),
+(25, 'shinobiwashirenmei', '忍びワシ連盟', 47, 48, 49, 'チーム「忍びワシ連盟」です。よろしくお願いします。', '313ca992948431aa'),
+(26, 'majomimizukukyoukai', '魔女ミミズク協会', 50, 51, -1, 'チーム「魔女ミミズク協会」です。よろしくお願いします。', '3aa0c5e9e61bf4e1'),
+(27, 'majokamomedesu', '魔女カモメです', 52, 53, 54, 'チーム「魔女カモメです」です。よろしくお願いします。', 'af06d57e3e0d0b24'),
+(28, 'majosaiguild', '魔女サイギルド', 55, 56, -1, 'チーム「魔女サイギルド」です。よろしくお願いします。', '87529aadc4764739'),
+(29, 'hyperfukuroudan', 'ハイパーフクロウ団', 57, 58, 59, 'チーム「ハイパーフクロウ団」です。よろしくお願いします。', '291f424a8e837b1b'),
+(30, 'saikyohagewashietal', '最強ハゲワシet al.', 60, 61, 62, 'チーム「最強ハゲワシet al.」です。よろしくお願いします。', 'd4c9836418dea063'),
+(31, 'tenshinokabanonakamatachi', '天使のカバの仲間たち', 63, 64, 65, 'チーム「天使のカバの仲間たち」です。よろしくお願いします。', '52f505dce00f85e7'),
+(32, 'yuushamogurarengou', '勇者モグラ連合', 66, -1, -1, 'チーム「勇者モグラ連合」です。よろしくお願いします。', 'b384c1e8e5f25231'),
+(33, 'specialfukurouclub', 'スペシャルフクロウクラブ', 67, 68, -1, 'チーム「スペシャルフクロウクラブ」です。よろしくお願いします。', 'fdb7994846f4eaba'),
+(34, 'chokamometeam', '超カモメチーム', 69, -1, -1, 'チーム「超カモメチーム」です。よろしくお願いします。', '1f6fc5edcff1dda1'),
+(35, 'ninjanezuminado', '忍者ネズミなど', 70, 71, -1, 'チーム「忍者ネズミなど」です。よろしくお願いします。', 'a000d5d797f24310'),
+(36, 'kyohamoguragroup', '今日はモグラグループ', 72, -1, -1, 'チーム「今日はモグラグループ」です。よろしくお願いします。', '08b04e5d23d5b786'),
+(37, 'samuraihagewashietal', '侍ハゲワシet al.', 73, 74, 75, 'チーム「侍ハゲワシet al.」です。よろしくお願いします。', '34c493bdaed15302'),
+(38, 'saikyoinudoumei', '最強イヌ同盟', 76, -1, -1, 'チーム「最強イヌ同盟」です。よろしくお願いします。', '3bc3e62eb8d0e3d8'),
+(39, 'saikyoinunominasan', '最強イヌの皆さん', 77, 78, -1, 'チーム「最強イヌの皆さん」です。よろしくお願いします。', '12a2d30ca9f9ae67'),
+(40, 'akumanohamusutatai', '悪魔のハムスター隊', 79, -1, -1, 'チーム「悪魔のハムスター隊」です。よろしくお願いします。', 'b579d7c6f9c56db5'),
+(41, 'maoutsubamerengou', '魔王ツバメ連合', 80, 81, 82, 'チーム「魔王ツバメ連合」です。よろしくお願いします。', 'f1c7aface81edd96'),
+(42, 'samuraimimizukurengou', '侍ミミズク連合', 83, 84, -1, 'チーム「侍ミミズク連合」です。よろしくお願いします。', 'e0d5692560a314d4'),
+(43, 'mysteriousryokuwotakamerugumi', 'ミステリアス力を高める組', 85, 86, 87, 'チーム「ミステリアス力を高める組」です。よろしくお願いします。', 'aed3f5546aa14fac'),
+(44, 'hyperhamusutateam', 'ハイパーハムスターチーム', 88, 89, -1, 'チーム「ハイパーハムスターチーム」です。よろしくお願いします。', '1500336fb93904da'),
+(45, 'tensaihagewashikumiai', '天才ハゲワシ組合', 90, 91, -1, 'チーム「天才ハゲワシ組合」です。よろしくお願いします。', '4d35e9eff7a58420'),
+(46, 'mysticalnekobu', 'ミスティカルネコ部', 92, -1, -1, 'チーム「ミスティカルネコ部」です。よろしくお願いします。', 'ab81446aa3ca1a15'),
+(47, 'mysteriousmorumottotoyukainanakamatachi', 'ミステリアスモルモットと愉快な仲間たち', 93, 94, 95, 'チーム「ミステリアスモルモットと愉快な仲間たち」です。よろしくお願いします。', 'a418774533bdd4d4'),
+(48, 'hypermomongarenmei', 'ハイパーモモンガ連盟', 96, 97, 98, 'チーム「ハイパーモモンガ連盟」です。よろしくお願いします。', '19a44d068f194112'),
+(49, 'sugoimorumottodesu', 'すごいモルモットです', 99, 100, -1, 'チーム「すごいモルモットです」です。よろしくお願いします。', 'e6d9e10f5f2b2cd5'),
+(50, 'superhagewashidesu', 'スーパーハゲワシです', 101, 102, 103, 'チーム「スーパーハゲワシです」です。よろしくお願いします。', 'edfc96d733e1ea29'),
+(51, 'mysterioushamusutakyoukai', 'ミステリアスハムスター協会', 104, -1, -1, 'チーム「ミステリアスハムスター協会」です。よろしくお願いします。', '9d2f61a3fe783804');
+DELETE FROM `tasks`;
+INSERT INTO `tasks` (`id`, `name`, `display_name`, `statement`, `submission_limit`) VALUES
+(1, 'A', '足し算', '足し算をしてください。', 10),
+(2, 'B', '引き算', '引き算をしてください。符号が間違っていた場合は、部分点として配点の 50 % の得点が与えられます。', 10);
+DELETE FROM `subtasks`;
+INSERT INTO `subtasks` (`id`, `name`, `display_name`, `task_id`, `statement`) VALUES
+(1, 'A_1', '(1)', 1, '$$ 1+1=\mathord{?} $$'),
+(2, 'A_2', '(2)', 1, '$$ 1+2=\mathord{?} $$'),
+(3, 'A_3', '(3)', 1, '$$ 2+2=\mathord{?} $$'),
+(4, 'A_4', '(4)', 1, '$$ 2+3=\mathord{?} $$'),
+(5, 'A_5', '(5)', 1, '$$ 3+3=\mathord{?} $$'),
+(6, 'B_1', '(1)', 2, '$$ 2-1=\mathord{?} $$'),
+(7, 'B_2', '(2)', 2, '$$ 3-1=\mathord{?} $$'),
+(8, 'B_3', '(3)', 2, '$$ 5-2=\mathord{?} $$'),
+(9, 'B_4', '(4)', 2, '$$ 1-5=\mathord{?} $$'),
+(10, 'B_5', '(5)', 2, '$$ 2-7=\mathord{?} $$');
+DELETE FROM `answers`;
+INSERT INTO `answers` (`id`, `task_id`, `subtask_id`, `answer`, `score`) VALUES
+(1, 1, 1, '2', 100),
+(2, 1, 2, '3', 100),
+(3, 1, 3, '4', 100),
+(4, 1, 4, '5', 100),
+(5, 1, 5, '6', 100),
+(6, 2, 6, '1', 100),
+(7, 2, 6, '-1', 50),
+(8, 2, 7, '2', 100),
+(9, 2, 7, '-2', 50),
+(10, 2, 8, '3', 100),
+(11, 2, 8, '-3', 50),
+(12, 2, 9, '-4', 100),
+(13, 2, 9, '4', 50),
+(14, 2, 10, '-5', 100),
+(15, 2, 10, '5', 50);
+DELETE FROM `submissions`;
+INSERT INTO `submissions` (`id`, `task_id`, `user_id`, `submitted_at`, `answer`) VALUES
+(1, 2, 66, '2024-03-26 18:00:00', '-2'),
+(2, 1, 8, '2024-03-26 18:00:01', 'てちなちれそさみれもえくいにむふれき'),
+(3, 2, 55, '2024-03-26 18:00:02', 'りちよらのそみせたつみろくとつおて'),
+(4, 1, 48, '2024-03-26 18:00:03', '4'),
+(5, 2, 7, '2024-03-26 18:00:04', 'まとまけうたけるもわなあよ'),
+(6, 1, 79, '2024-03-26 18:00:05', '4'),
+(7, 2, 28, '2024-03-26 18:00:06', '-5'),
+(8, 2, 81, '2024-03-26 18:00:07', '4'),
+(9, 1, 12, '2024-03-26 18:00:08', '3'),
+(10, 1, 45, '2024-03-26 18:00:09', 'ちまちりまたこみいそもるわぬむもき'),
+(11, 1, 87, '2024-03-26 18:00:10', 'をとんろやすえせにならはた'),
+(12, 1, 61, '2024-03-26 18:00:11', '6'),
+(13, 2, 102, '2024-03-26 18:00:12', '-2'),
+(14, 2, 29, '2024-03-26 18:00:13', '-3'),
+(15, 2, 48, '2024-03-26 18:00:14', '4'),
+(16, 2, 99, '2024-03-26 18:00:15', '5'),
+(17, 1, 17, '2024-03-26 18:00:16', '2'),
+(18, 2, 53, '2024-03-26 18:00:17', '-1'),
+(19, 2, 76, '2024-03-26 18:00:18', '3'),
+(20, 1, 75, '2024-03-26 18:00:19', '5'),
+(21, 2, 72, '2024-03-26 18:00:20', '-1'),
+(22, 1, 37, '2024-03-26 18:00:21', '4'),
+(23, 1, 80, '2024-03-26 18:00:22', '3'),
+(24, 2, 42, '2024-03-26 18:00:23', 'はかもよみあえちちちきかぬしをい'),
+(25, 2, 22, '2024-03-26 18:00:24', '3'),
+(26, 1, 94, '2024-03-26 18:00:25', '4'),
+(27, 2, 72, '2024-03-26 18:00:26', '3'),
+(28, 1, 18, '2024-03-26 18:00:27', '2'),
+(29, 2, 77, '2024-03-26 18:00:28', '1'),
+(30, 1, 46, '2024-03-26 18:00:29', '6'),
+(31, 1, 98, '2024-03-26 18:00:30', '3'),
+(32, 1, 72, '2024-03-26 18:00:31', '3'),
+(33, 2, 83, '2024-03-26 18:00:32', 'おとちなひもせまろりくせに'),
+(34, 2, 11, '2024-03-26 18:00:33', '-5'),
+(35, 2, 17, '2024-03-26 18:00:34', '4'),
+(36, 2, 20, '2024-03-26 18:00:35', '4'),
+(37, 2, 28, '2024-03-26 18:00:36', '-4'),
+(38, 2, 99, '2024-03-26 18:00:37', '1'),
+(39, 1, 60, '2024-03-26 18:00:38', '2'),
+(40, 2, 14, '2024-03-26 18:00:39', 'にこくらなそつむらかきたみゆしろな'),
+(41, 1, 32, '2024-03-26 18:00:40', '2'),
+(42, 2, 92, '2024-03-26 18:00:41', '-3'),
+(43, 1, 49, '2024-03-26 18:00:42', '5'),
+(44, 1, 85, '2024-03-26 18:00:43', '2'),
+(45, 1, 23, '2024-03-26 18:00:44', '4'),
+(46, 1, 33, '2024-03-26 18:00:45', '2'),
+(47, 1, 93, '2024-03-26 18:00:46', '4'),
+(48, 2, 15, '2024-03-26 18:00:47', '1'),
+(49, 1, 97, '2024-03-26 18:00:48', '3'),
+(50, 2, 64, '2024-03-26 18:00:49', '5'),
+(51, 2, 48, '2024-03-26 18:00:50', '3'),
+(52, 1, 12, '2024-03-26 18:00:51', '3'),
+(53, 2, 98, '2024-03-26 18:00:52', 'そとなぬあわみのけおえきみひくかめる'),
+(54, 2, 40, '2024-03-26 18:00:53', '4'),
+(55, 2, 99, '2024-03-26 18:00:54', 'むさめもむよたはれつやすわむ'),
+(56, 2, 38, '2024-03-26 18:00:55', 'やくちんえひみもうゆをたつねはなりれ'),
+(57, 1, 88, '2024-03-26 18:00:56', '5'),
+(58, 1, 68, '2024-03-26 18:00:57', '4'),
+(59, 2, 47, '2024-03-26 18:00:58', '-4'),
+(60, 2, 103, '2024-03-26 18:00:59', '-5'),
+(61, 1, 68, '2024-03-26 18:01:00', '6'),
+(62, 1, 39, '2024-03-26 18:01:01', '6'),
+(63, 1, 59, '2024-03-26 18:01:02', '3'),
+(64, 1, 29, '2024-03-26 18:01:03', '3'),
+(65, 1, 76, '2024-03-26 18:01:04', '6'),
+(66, 2, 41, '2024-03-26 18:01:05', '-5'),
+(67, 2, 75, '2024-03-26 18:01:06', 'わももるへまくれんにはねせろはねもへぬ'),
+(68, 1, 40, '2024-03-26 18:01:07', 'ぬとひさかてるいぬるはんへかやれ'),
+(69, 2, 98, '2024-03-26 18:01:08', '-3'),
+(70, 2, 45, '2024-03-26 18:01:09', 'へもいももたえるりんつとてみふわちらの'),
+(71, 1, 45, '2024-03-26 18:01:10', '3'),
+(72, 1, 13, '2024-03-26 18:01:11', '6'),
+(73, 1, 56, '2024-03-26 18:01:12', 'あむひちらけゆそむとぬなろはね'),
+(74, 1, 39, '2024-03-26 18:01:13', '6'),
+(75, 1, 58, '2024-03-26 18:01:14', 'めるあすんまえとなすね'),
+(76, 2, 21, '2024-03-26 18:01:15', '-2'),
+(77, 1, 22, '2024-03-26 18:01:16', '4'),
+(78, 2, 87, '2024-03-26 18:01:17', '4'),
+(79, 2, 100, '2024-03-26 18:01:18', '-4'),
+(80, 2, 86, '2024-03-26 18:01:19', '-3'),
+(81, 2, 59, '2024-03-26 18:01:20', '-1'),
+(82, 2, 7, '2024-03-26 18:01:21', '-5'),
+(83, 1, 58, '2024-03-26 18:01:22', '2'),
+(84, 1, 50, '2024-03-26 18:01:23', '5'),
+(85, 1, 27, '2024-03-26 18:01:24', '3'),
+(86, 2, 74, '2024-03-26 18:01:25', '-3'),
+(87, 1, 71, '2024-03-26 18:01:26', '5'),
+(88, 2, 84, '2024-03-26 18:01:27', '1'),
+(89, 1, 54, '2024-03-26 18:01:28', '5'),
+(90, 2, 35, '2024-03-26 18:01:29', '2'),
+(91, 1, 13, '2024-03-26 18:01:30', '2'),
+(92, 2, 21, '2024-03-26 18:01:31', '4'),
+(93, 1, 64, '2024-03-26 18:01:32', '6'),
+(94, 1, 59, '2024-03-26 18:01:33', 'いこまたよきりやひとゆわけひ'),
+(95, 2, 3, '2024-03-26 18:01:34', '-1'),
+(96, 2, 14, '2024-03-26 18:01:35', 'そけふんれくうきひくねる'),
+(97, 1, 36, '2024-03-26 18:01:36', 'るやたむみくめんあものはやるぬ'),
+(98, 1, 104, '2024-03-26 18:01:37', '5'),
+(99, 2, 42, '2024-03-26 18:01:38', '-2'),
+(100, 1, 8, '2024-03-26 18:01:39', 'むもはりめはむのみらおえをよのた'),
+(101, 1, 83, '2024-03-26 18:01:40', '6'),
+(102, 2, 94, '2024-03-26 18:01:41', '-4'),
+(103, 1, 74, '2024-03-26 18:01:42', '4'),
+(104, 2, 57, '2024-03-26 18:01:43', '3'),
+(105, 2, 22, '2024-03-26 18:01:44', 'つまつちさこへひいつなけあくひをす'),
+(106, 1, 63, '2024-03-26 18:01:45', '4'),
+(107, 1, 49, '2024-03-26 18:01:46', 'あむねるひやひわてもわろつにらよた'),
+(108, 2, 23, '2024-03-26 18:01:47', '5'),
+(109, 1, 31, '2024-03-26 18:01:48', 'すぬのぬついぬたきれらもこ'),
+(110, 1, 4, '2024-03-26 18:01:49', '6'),
+(111, 1, 26, '2024-03-26 18:01:50', '6'),
+(112, 2, 18, '2024-03-26 18:01:51', '-4'),
+(113, 1, 82, '2024-03-26 18:01:52', '3'),
+(114, 1, 104, '2024-03-26 18:01:53', 'ぬりよすものゆおへお'),
+(115, 2, 70, '2024-03-26 18:01:54', '5'),
+(116, 1, 57, '2024-03-26 18:01:55', 'うつうもむややいゆひむしはこへね'),
+(117, 1, 100, '2024-03-26 18:01:56', 'るあねゆはねちをとおきもお'),
+(118, 2, 85, '2024-03-26 18:01:57', '-1'),
+(119, 2, 84, '2024-03-26 18:01:58', '3'),
+(120, 1, 3, '2024-03-26 18:01:59', '6'),
+(121, 2, 96, '2024-03-26 18:02:00', '4'),
+(122, 1, 44, '2024-03-26 18:02:01', '2'),
+(123, 1, 92, '2024-03-26 18:02:02', 'うんたこぬふとつかひへいに'),
+(124, 1, 41, '2024-03-26 18:02:03', '3'),
+(125, 2, 89, '2024-03-26 18:02:04', 'うむちらそやりそひをめのよ'),
+(126, 2, 42, '2024-03-26 18:02:05', '-1'),
+(127, 1, 30, '2024-03-26 18:02:06', '3'),
+(128, 1, 16, '2024-03-26 18:02:07', '6'),
+(129, 1, 24, '2024-03-26 18:02:08', '6'),
+(130, 1, 66, '2024-03-26 18:02:09', 'あにきかみはんふをるらへいよなろをもこ'),
+(131, 1, 52, '2024-03-26 18:02:10', '2'),
+(132, 2, 19, '2024-03-26 18:02:11', '-4'),
+(133, 1, 73, '2024-03-26 18:02:12', '6'),
+(134, 1, 69, '2024-03-26 18:02:13', '4'),
+(135, 1, 2, '2024-03-26 18:02:14', '6'),
+(136, 2, 2, '2024-03-26 18:02:15', '5'),
+(137, 2, 9, '2024-03-26 18:02:16', '4'),
+(138, 1, 49, '2024-03-26 18:02:17', '3'),
+(139, 2, 81, '2024-03-26 18:02:18', '-3'),
+(140, 2, 82, '2024-03-26 18:02:19', '-1'),
+(141, 2, 24, '2024-03-26 18:02:20', 'ぬならわをあむすせもろうとたぬ'),
+(142, 1, 46, '2024-03-26 18:02:21', 'てたやけといるもぬたやへる'),
+(143, 2, 34, '2024-03-26 18:02:22', 'るもにさんらもかちろわわ'),
+(144, 2, 35, '2024-03-26 18:02:23', '4'),
+(145, 2, 101, '2024-03-26 18:02:24', 'おそさむりけむとるへふかつうし'),
+(146, 1, 38, '2024-03-26 18:02:25', 'たらおふけそもわちんくす'),
+(147, 1, 14, '2024-03-26 18:02:26', '2'),
+(148, 1, 75, '2024-03-26 18:02:27', '4'),
+(149, 2, 68, '2024-03-26 18:02:28', '-4'),
+(150, 1, 15, '2024-03-26 18:02:29', '4'),
+(151, 1, 67, '2024-03-26 18:02:30', '3'),
+(152, 1, 64, '2024-03-26 18:02:31', 'みゆつもおよきこゆあめききつせをのにて'),
+(153, 2, 62, '2024-03-26 18:02:32', '-2'),
+(154, 2, 88, '2024-03-26 18:02:33', '-5'),
+(155, 2, 100, '2024-03-26 18:02:34', '-4'),
+(156, 1, 65, '2024-03-26 18:02:35', '4'),
+(157, 2, 80, '2024-03-26 18:02:36', '5'),
+(158, 1, 95, '2024-03-26 18:02:37', '3'),
+(159, 1, 32, '2024-03-26 18:02:38', 'もあえそきおるらなそねふまもあちててえ'),
+(160, 1, 30, '2024-03-26 18:02:39', 'あみすたおめもろさたんゆみえきおむみる'),
+(161, 1, 35, '2024-03-26 18:02:40', '3'),
+(162, 1, 40, '2024-03-26 18:02:41', '4'),
+(163, 1, 21, '2024-03-26 18:02:42', '5'),
+(164, 1, 34, '2024-03-26 18:02:43', '5'),
+(165, 2, 36, '2024-03-26 18:02:44', '-2'),
+(166, 2, 58, '2024-03-26 18:02:45', '-2'),
+(167, 1, 7, '2024-03-26 18:02:46', '6'),
+(168, 1, 63, '2024-03-26 18:02:47', '5'),
+(169, 1, 95, '2024-03-26 18:02:48', '5'),
+(170, 2, 70, '2024-03-26 18:02:49', '-2'),
+(171, 2, 15, '2024-03-26 18:02:50', '-5'),
+(172, 2, 13, '2024-03-26 18:02:51', 'もねめれとくかきいねのやにけ'),
+(173, 1, 4, '2024-03-26 18:02:52', '5'),
+(174, 1, 3, '2024-03-26 18:02:53', '3'),
+(175, 2, 2, '2024-03-26 18:02:54', 'おりてひをへんれみせそひろせてもまれみ'),
+(176, 2, 34, '2024-03-26 18:02:55', '2'),
+(177, 1, 43, '2024-03-26 18:02:56', 'すへせたさちふらしこそなへをいゆやなに'),
+(178, 2, 43, '2024-03-26 18:02:57', '2'),
+(179, 1, 82, '2024-03-26 18:02:58', '2'),
+(180, 1, 20, '2024-03-26 18:02:59', '2'),
+(181, 1, 102, '2024-03-26 18:03:00', '3'),
+(182, 1, 103, '2024-03-26 18:03:01', '5'),
+(183, 1, 5, '2024-03-26 18:03:02', '3'),
+(184, 1, 25, '2024-03-26 18:03:03', '3'),
+(185, 2, 93, '2024-03-26 18:03:04', '-3'),
+(186, 1, 5, '2024-03-26 18:03:05', '5'),
+(187, 1, 10, '2024-03-26 18:03:06', '5'),
+(188, 1, 31, '2024-03-26 18:03:07', '2'),
+(189, 2, 44, '2024-03-26 18:03:08', '2'),
+(190, 2, 71, '2024-03-26 18:03:09', '5'),
+(191, 2, 89, '2024-03-26 18:03:10', 'おふはしとわふきいるいひてふあやき'),
+(192, 2, 33, '2024-03-26 18:03:11', 'わもそさくのりぬけやわおわれすかろし'),
+(193, 1, 44, '2024-03-26 18:03:12', '2'),
+(194, 1, 43, '2024-03-26 18:03:13', '5'),
+(195, 1, 80, '2024-03-26 18:03:14', '6'),
+(196, 2, 67, '2024-03-26 18:03:15', '5'),
+(197, 2, 83, '2024-03-26 18:03:16', '-4'),
+(198, 1, 19, '2024-03-26 18:03:17', '4'),
+(199, 2, 78, '2024-03-26 18:03:18', '3'),
+(200, 2, 78, '2024-03-26 18:03:19', '-5'),
+(201, 1, 36, '2024-03-26 18:03:20', '4'),
+(202, 2, 25, '2024-03-26 18:03:21', '-3'),
+(203, 2, 54, '2024-03-26 18:03:22', '5'),
+(204, 2, 10, '2024-03-26 18:03:23', 'もうさやふもさすつはなすそ'),
+(205, 2, 53, '2024-03-26 18:03:24', '2'),
+(206, 1, 54, '2024-03-26 18:03:25', '5'),
+(207, 2, 94, '2024-03-26 18:03:26', 'にえねなそてかるひかめつにつてひはい'),
+(208, 1, 81, '2024-03-26 18:03:27', '5'),
+(209, 1, 102, '2024-03-26 18:03:28', '5'),
+(210, 2, 90, '2024-03-26 18:03:29', '1'),
+(211, 1, 57, '2024-03-26 18:03:30', '4'),
+(212, 2, 26, '2024-03-26 18:03:31', '1'),
+(213, 2, 65, '2024-03-26 18:03:32', '-2'),
+(214, 2, 19, '2024-03-26 18:03:33', '-2'),
+(215, 1, 11, '2024-03-26 18:03:34', 'むかまうとまむさたんちうなはむいよ'),
+(216, 2, 50, '2024-03-26 18:03:35', '-3'),
+(217, 2, 61, '2024-03-26 18:03:36', '4'),
+(218, 2, 104, '2024-03-26 18:03:37', '-3'),
+(219, 2, 52, '2024-03-26 18:03:38', '-4'),
+(220, 1, 20, '2024-03-26 18:03:39', 'もへさるらんるにふはりもうんをそりめ'),
+(221, 1, 10, '2024-03-26 18:03:40', '3'),
+(222, 2, 69, '2024-03-26 18:03:41', '-3'),
+(223, 2, 91, '2024-03-26 18:03:42', '-4'),
+(224, 2, 71, '2024-03-26 18:03:43', 'けはにぬわをみみりなうのひしれきれはち'),
+(225, 2, 4, '2024-03-26 18:03:44', 'ああしつそわわたぬせりつとたむうえろん'),
+(226, 1, 66, '2024-03-26 18:03:45', '2'),
+(227, 1, 29, '2024-03-26 18:03:46', '5'),
+(228, 1, 87, '2024-03-26 18:03:47', '6'),
+(229, 1, 31, '2024-03-26 18:03:48', '5'),
+(230, 2, 37, '2024-03-26 18:03:49', '-3'),
+(231, 1, 28, '2024-03-26 18:03:50', '5'),
+(232, 1, 77, '2024-03-26 18:03:51', '2'),
+(233, 2, 55, '2024-03-26 18:03:52', '-1'),
+(234, 2, 9, '2024-03-26 18:03:53', '-5'),
+(235, 1, 51, '2024-03-26 18:03:54', '3'),
+(236, 1, 6, '2024-03-26 18:03:55', '3'),
+(237, 2, 93, '2024-03-26 18:03:56', '1'),
+(238, 1, 41, '2024-03-26 18:03:57', '3'),
+(239, 2, 88, '2024-03-26 18:03:58', '1'),
+(240, 2, 89, '2024-03-26 18:03:59', '1'),
+(241, 2, 62, '2024-03-26 18:04:00', '2'),
+(242, 1, 67, '2024-03-26 18:04:01', '4'),
+(243, 2, 84, '2024-03-26 18:04:02', '-1'),
+(244, 2, 61, '2024-03-26 18:04:03', 'およりえらんぬとりふわいつふけさ'),
+(245, 1, 11, '2024-03-26 18:04:04', '4'),
+(246, 1, 6, '2024-03-26 18:04:05', 'なによねかゆきてはもててすひ'),
+(247, 1, 76, '2024-03-26 18:04:06', '2'),
+(248, 2, 73, '2024-03-26 18:04:07', '2'),
+(249, 1, 16, '2024-03-26 18:04:08', '3'),
+(250, 1, 97, '2024-03-26 18:04:09', 'えしへんにめよおやみくふくにぬゆもにせ'),
+(251, 1, 101, '2024-03-26 18:04:10', 'もにとますれけなすねきねみりしへ'),
+(252, 1, 6, '2024-03-26 18:04:11', '5'),
+(253, 1, 33, '2024-03-26 18:04:12', '6'),
+(254, 2, 47, '2024-03-26 18:04:13', '1'),
+(255, 1, 5, '2024-03-26 18:04:14', '5'),
+(256, 1, 27, '2024-03-26 18:04:15', '5'),
+(257, 2, 47, '2024-03-26 18:04:16', '5'),
+(258, 2, 24, '2024-03-26 18:04:17', '-4'),
+(259, 2, 55, '2024-03-26 18:04:18', '-5'),
+(260, 2, 79, '2024-03-26 18:04:19', 'ゆくをいこわみくせえのそに'),
+(261, 1, 30, '2024-03-26 18:04:20', '6'),
+(262, 1, 39, '2024-03-26 18:04:21', '4'),
+(263, 1, 18, '2024-03-26 18:04:22', '4'),
+(264, 1, 86, '2024-03-26 18:04:23', 'もろつをちなよいなろこもも'),
+(265, 2, 27, '2024-03-26 18:04:24', '4'),
+(266, 1, 91, '2024-03-26 18:04:25', 'ねみらすろよれまねめるとそわめ'),
+(267, 1, 79, '2024-03-26 18:04:26', '3'),
+(268, 2, 63, '2024-03-26 18:04:27', '-5'),
+(269, 2, 8, '2024-03-26 18:04:28', '-5'),
+(270, 2, 101, '2024-03-26 18:04:29', '-3'),
+(271, 2, 69, '2024-03-26 18:04:30', '1'),
+(272, 2, 51, '2024-03-26 18:04:31', 'うてやてこれぬけめちねゆみな'),
+(273, 1, 9, '2024-03-26 18:04:32', '2'),
+(274, 1, 70, '2024-03-26 18:04:33', 'すてんははにへえてやぬわ'),
+(275, 1, 32, '2024-03-26 18:04:34', 'へめれみねなめきふてふ'),
+(276, 2, 23, '2024-03-26 18:04:35', '2'),
+(277, 2, 96, '2024-03-26 18:04:36', '3'),
+(278, 2, 92, '2024-03-26 18:04:37', '5'),
+(279, 1, 91, '2024-03-26 18:04:38', '6'),
+(280, 2, 90, '2024-03-26 18:04:39', '3'),
+(281, 2, 74, '2024-03-26 18:04:40', '2'),
+(282, 1, 77, '2024-03-26 18:04:41', '2'),
+(283, 2, 56, '2024-03-26 18:04:42', '-4'),
+(284, 1, 12, '2024-03-26 18:04:43', '2'),
+(285, 1, 56, '2024-03-26 18:04:44', '3'),
+(286, 2, 16, '2024-03-26 18:04:45', '-5'),
+(287, 1, 17, '2024-03-26 18:04:46', '2'),
+(288, 1, 90, '2024-03-26 18:04:47', '6'),
+(289, 1, 50, '2024-03-26 18:04:48', '5'),
+(290, 1, 60, '2024-03-26 18:04:49', '4'),
+(291, 1, 52, '2024-03-26 18:04:50', '6'),
+(292, 2, 26, '2024-03-26 18:04:51', '4'),
+(293, 2, 38, '2024-03-26 18:04:52', '3'),
+(294, 2, 78, '2024-03-26 18:04:53', '1'),
+(295, 1, 65, '2024-03-26 18:04:54', '2'),
+(296, 2, 25, '2024-03-26 18:04:55', 'よあせぬのかつてるすえり'),
+(297, 1, 103, '2024-03-26 18:04:56', 'てたみおてへてもこれせてるおもうち'),
+(298, 1, 96, '2024-03-26 18:04:57', 'ゆてせあせさろめねすま'),
+(299, 2, 95, '2024-03-26 18:04:58', 'へきやみぬへはとぬとかもまさうとま'),
+(300, 2, 97, '2024-03-26 18:04:59', 'んけしるかゆらぬもたしれらめねえ'),
+(301, 1, 46, '2024-03-26 18:05:00', 'むうつみさもめのさひ'),
+(302, 1, 85, '2024-03-26 18:05:01', '4'),
+(303, 1, 53, '2024-03-26 18:05:02', '4'),
+(304, 1, 62, '2024-03-26 18:05:03', '2'),
+(305, 2, 73, '2024-03-26 18:05:04', 'うれすすせまめもふゆちやか'),
+(306, 2, 60, '2024-03-26 18:05:05', 'みぬてねそきてゆかあて'),
+(307, 1, 51, '2024-03-26 18:05:06', '5'),
+(308, 2, 86, '2024-03-26 18:05:07', '5'),
+(309, 2, 37, '2024-03-26 18:05:08', '5');
+-- 初期データの提出は、送られてきた時刻に受け取ったことにする
+UPDATE `submissions` SET `received_at` = `submitted_at`;